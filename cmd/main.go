@@ -1,19 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"main/config"
 	"main/internal/fetcher"
 	"main/internal/handler"
 	"main/internal/storage"
+	"main/pkg/cache"
+	"main/pkg/leader"
 	"main/pkg/postgres"
 	"main/pkg/telegram"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/net/context"
 )
 
 func main() {
@@ -26,10 +29,6 @@ func main() {
 	if err != nil {
 		log.Fatalf("cannot create new config: %v", err)
 	}
-	b, err := telegram.NewBot(ctx, c.Telegram)
-	if err != nil {
-		log.Fatalf("cannot create new telegram bot: %v", err)
-	}
 	p, err := postgres.NewClient(ctx, c.Postgres)
 	if err != nil {
 		log.Fatalf("cannot create new postgres client: %v", err)
@@ -37,16 +36,50 @@ func main() {
 	s := storage.NewStorage(ctx, p)
 	f := fetcher.NewFetcher(ctx, c.Proxy)
 
-	h, err := handler.NewHandler(ctx, b, f, s)
+	cb, err := cache.NewBackend(ctx, c.Cache)
+	if err != nil {
+		log.Fatalf("cannot create new cache backend: %v", err)
+	}
+	b, err := telegram.NewBot(ctx, c.Telegram, cb)
+	if err != nil {
+		log.Fatalf("cannot create new telegram bot: %v", err)
+	}
+	// clients live until exit, while work is stopped by signal before handler shutdown
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	h, err := handler.NewHandler(runCtx, b, f, s, cb, c.Handler)
 	if err != nil {
 		log.Fatalf("cannot create new handler: %v", err)
 	}
 	defer h.Shutdown()
 
-	go h.HandleMessagesContinuously(ctx)
-	go h.HandleSubscriptionsContinuously(ctx)
+	// messages are handled until bot is shutdown
+	go h.HandleMessagesContinuously(runCtx)
+
+	wg := sync.WaitGroup{}
+
+	for _, run := range []func(ctx context.Context){
+		h.HandleDeliveriesContinuously,
+		h.HandleShardsContinuously,
+		h.HandleEventsContinuously,
+		// only elected leader polls updates and runs schedules, deliveries are shared by leases
+		// and subscriptions are sharded between instances
+		func(ctx context.Context) {
+			leader.NewElector(p, c.Leader).Run(ctx, h.HandleLeading)
+		},
+	} {
+		run := run
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			run(runCtx)
+		}()
+	}
+	<-runCtx.Done()
 
-	exit := make(chan os.Signal)
-	signal.Notify(exit, syscall.SIGINT, syscall.SIGTERM)
-	<-exit
+	// producers are stopped before handler drains queued tasks
+	log.Infof("shutdown signal received. waiting handlers stop")
+	wg.Wait()
 }
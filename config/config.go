@@ -2,17 +2,27 @@ package config
 
 import (
 	"fmt"
+	"main/internal/handler"
+	"main/pkg/cache"
+	"main/pkg/leader"
 	"main/pkg/postgres"
+	"main/pkg/telegram"
 	"main/pkg/validation"
 	"os"
 
 	"gopkg.in/yaml.v2"
 )
 
+// callbackSecretEnv keeps callback secret out of config file, it is used if secret is not set in config.
+const callbackSecretEnv = "TELEGRAM_CALLBACK_SECRET"
+
 type Config struct {
 	Postgres *postgres.Config `yaml:"postgres" required:"true"`
-	Telegram string           `yaml:"telegram" required:"true"`
+	Telegram *telegram.Config `yaml:"telegram" required:"true"`
 	Proxy    string           `yaml:"proxy"`
+	Leader   *leader.Config   `yaml:"leader"`
+	Cache    *cache.Config    `yaml:"cache"`
+	Handler  *handler.Config  `yaml:"handler"`
 }
 
 func NewConfig(file string) (*Config, error) {
@@ -28,5 +38,21 @@ func NewConfig(file string) (*Config, error) {
 	if err = validation.ValidateStructFields(config); err != nil {
 		return nil, fmt.Errorf("cannot validate config struct: %v", err)
 	}
+	if err = config.setCallbackSecret(); err != nil {
+		return nil, fmt.Errorf("cannot set callback secret: %v", err)
+	}
 	return config, nil
 }
+
+func (c *Config) setCallbackSecret() error {
+	if c.Telegram.Callback == nil {
+		c.Telegram.Callback = &telegram.CallbackConfig{}
+	}
+	if c.Telegram.Callback.Secret == "" {
+		c.Telegram.Callback.Secret = os.Getenv(callbackSecretEnv)
+	}
+	if c.Telegram.Callback.Secret == "" {
+		return fmt.Errorf("telegram.callback.secret is not specified in config and %s env is empty", callbackSecretEnv)
+	}
+	return nil
+}
@@ -2,64 +2,86 @@ package chats
 
 import (
 	"context"
-	"main/pkg/tree"
-	"sync"
+	"encoding/json"
 )
 
-type Link string
-
-type State struct {
-	MessageID int64
-	Event     func(input *EventInput) (messageID int64, err error)
-}
-
 type EventInput struct {
-	Ctx     context.Context
-	ChatID  int64
-	UserID  int64
-	Text    string
-	Command string
+	Ctx        context.Context
+	ChatID     int64
+	UserID     int64
+	Text       string
+	Command    string
+	CallbackID string
 }
 
-type Trees interface {
-	Tree(chatID int64) tree.Tree[Link, *State]
-	SetTree(chatID int64, t tree.Tree[Link, *State])
-	RebuildTree(chatID int64) tree.Tree[Link, *State]
+// Event passed to state hooks and transitions with parsed command and chat session.
+type Event[D any] struct {
+	Ctx        context.Context
+	ChatID     int64
+	UserID     int64
+	Text       string
+	CallbackID string
+	Action     Action
+	Params     Params
+	Session    *Session[D]
 }
 
-type trees struct {
-	mtx       sync.Mutex
-	chatTrees map[int64]tree.Tree[Link, *State]
-	buildTree func() tree.Tree[Link, *State]
+// Session keeps dialog state of chat between updates.
+type Session[D any] struct {
+	ChatID int64
+	// MessageID is dialog message which edited on transitions
+	MessageID int64
+	Data      D
+	state     StateName
+	history   []StateName
 }
 
-func NewChatsTrees(buildTree func() tree.Tree[Link, *State]) Trees {
-	return &trees{
-		chatTrees: map[int64]tree.Tree[Link, *State]{},
-		buildTree: buildTree,
-	}
+func (s *Session[D]) State() StateName {
+	return s.state
 }
 
-func (trs *trees) Tree(chatID int64) tree.Tree[Link, *State] {
-	trs.mtx.Lock()
-	defer trs.mtx.Unlock()
-
-	if t, ok := trs.chatTrees[chatID]; ok {
-		return t
+func newEvent[D any](input *EventInput, action Action, params Params, session *Session[D]) *Event[D] {
+	return &Event[D]{
+		Ctx:        input.Ctx,
+		ChatID:     input.ChatID,
+		UserID:     input.UserID,
+		Text:       input.Text,
+		CallbackID: input.CallbackID,
+		Action:     action,
+		Params:     params,
+		Session:    session,
 	}
-	trs.chatTrees[chatID] = trs.buildTree()
+}
 
-	return trs.chatTrees[chatID]
+type sessionJSON[D any] struct {
+	ChatID    int64       `json:"chat_id"`
+	MessageID int64       `json:"message_id"`
+	Data      D           `json:"data"`
+	State     StateName   `json:"state"`
+	History   []StateName `json:"history"`
 }
 
-func (trs *trees) SetTree(chatID int64, t tree.Tree[Link, *State]) {
-	trs.chatTrees[chatID] = t
+func (s *Session[D]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&sessionJSON[D]{
+		ChatID:    s.ChatID,
+		MessageID: s.MessageID,
+		Data:      s.Data,
+		State:     s.state,
+		History:   s.history,
+	})
 }
 
-func (trs *trees) RebuildTree(chatID int64) tree.Tree[Link, *State] {
-	trs.mtx.Lock()
-	defer trs.mtx.Unlock()
+func (s *Session[D]) UnmarshalJSON(buf []byte) error {
+	v := &sessionJSON[D]{}
+
+	if err := json.Unmarshal(buf, v); err != nil {
+		return err
+	}
+	s.ChatID = v.ChatID
+	s.MessageID = v.MessageID
+	s.Data = v.Data
+	s.state = v.State
+	s.history = v.History
 
-	trs.chatTrees[chatID] = trs.buildTree()
-	return trs.chatTrees[chatID]
+	return nil
 }
@@ -0,0 +1,282 @@
+package chats
+
+import (
+	"fmt"
+	"main/pkg/cache"
+	"sync"
+	"time"
+)
+
+const (
+	// BackAction returns dialog to previous state from history
+	BackAction Action = "back"
+
+	maxHistory = 32
+)
+
+type StateName string
+
+// Hook called on state entry or exit.
+type Hook[D any] func(e *Event[D]) error
+
+// Transition moves dialog to state To. Do runs before transition and may redirect it by returning other state.
+type Transition[D any] struct {
+	To StateName
+	Do func(e *Event[D]) (StateName, error)
+	// Replace does not push current state to history, so back skips it
+	Replace bool
+	// Reset clears history and session data
+	Reset bool
+}
+
+type State[D any] struct {
+	Name StateName
+	// OnEnter renders state, e.g. edits dialog message
+	OnEnter Hook[D]
+	OnExit  Hook[D]
+	// OnText handles text typed in state and returns next state which replaces current
+	OnText func(e *Event[D]) (StateName, error)
+	// Final state ends session after entry
+	Final       bool
+	Transitions map[Action]Transition[D]
+}
+
+type DialogConfig[D any] struct {
+	NewData func() D
+	// SessionTTL drops session if chat was inactive, MaxSessions evicts least recently active sessions
+	SessionTTL  time.Duration
+	MaxSessions int
+	// Global transitions available from any state
+	Global map[Action]Transition[D]
+	// Sessions stores chats sessions, e.g. in redis to share them between instances.
+	// Memory cache with SessionTTL and MaxSessions is used by default
+	Sessions cache.MemCache[int64, *Session[D]]
+}
+
+type Dialog[D any] interface {
+	HandleCommand(input *EventInput) error
+	HandleText(input *EventInput) error
+	Session(chatID int64) (*Session[D], bool)
+	Reset(chatID int64)
+	// Sweep removes expired sessions and returns count of removed sessions
+	Sweep() int
+}
+
+type dialog[D any] struct {
+	mtx      sync.Mutex
+	config   DialogConfig[D]
+	states   map[StateName]*State[D]
+	sessions cache.MemCache[int64, *Session[D]]
+}
+
+func NewDialog[D any](config DialogConfig[D], states ...*State[D]) (Dialog[D], error) {
+	d := &dialog[D]{
+		config:   config,
+		states:   make(map[StateName]*State[D], len(states)),
+		sessions: config.Sessions,
+	}
+	if d.sessions == nil {
+		d.sessions = cache.NewMemCache[int64, *Session[D]](
+			cache.WithTTL(config.SessionTTL),
+			cache.WithMaxSize(config.MaxSessions),
+		)
+	}
+	for _, state := range states {
+		if _, ok := d.states[state.Name]; ok {
+			return nil, fmt.Errorf("state %s declared twice", state.Name)
+		}
+		d.states[state.Name] = state
+	}
+	// check transitions targets once on declaration
+	check := func(transitions map[Action]Transition[D]) error {
+		for action, t := range transitions {
+			if action == BackAction {
+				return fmt.Errorf("action %s is reserved", action)
+			}
+			if _, ok := d.states[t.To]; !ok && t.To != "" {
+				return fmt.Errorf("transition %s to unknown state %s", action, t.To)
+			}
+			if t.To == "" && t.Do == nil {
+				return fmt.Errorf("transition %s has no target state", action)
+			}
+		}
+		return nil
+	}
+	if err := check(config.Global); err != nil {
+		return nil, fmt.Errorf("cannot check global transitions: %v", err)
+	}
+	for _, state := range states {
+		if err := check(state.Transitions); err != nil {
+			return nil, fmt.Errorf("cannot check state %s transitions: %v", state.Name, err)
+		}
+	}
+	return d, nil
+}
+
+func (d *dialog[D]) HandleCommand(input *EventInput) error {
+	action, params, err := ParseCommand(input.Command)
+	if err != nil {
+		return err
+	}
+	session, created := d.session(input.ChatID)
+	e := newEvent(input, action, params, session)
+
+	if action == BackAction {
+		if len(session.history) == 0 {
+			if created {
+				d.Reset(input.ChatID)
+			}
+			return nil
+		}
+		prev := session.history[len(session.history)-1]
+		session.history = session.history[:len(session.history)-1]
+
+		return d.transit(e, prev)
+	}
+	t, ok := d.transition(session.state, action)
+	if !ok {
+		// command not expected in current state, e.g. button of old message
+		if created {
+			d.Reset(input.ChatID)
+		}
+		return nil
+	}
+	to := t.To
+
+	if t.Do != nil {
+		next, err := t.Do(e)
+		if err != nil {
+			return fmt.Errorf("cannot do %s transition: %v", action, err)
+		}
+		if next != "" {
+			to = next
+		}
+	}
+	if _, ok := d.states[to]; !ok {
+		return fmt.Errorf("transition %s to unknown state %s", action, to)
+	}
+	switch {
+	case t.Reset:
+		session.history = nil
+		session.Data = d.newData()
+
+	case !t.Replace && session.state != "" && session.state != to:
+		d.pushHistory(session)
+	}
+	return d.transit(e, to)
+}
+
+func (d *dialog[D]) HandleText(input *EventInput) error {
+	session, ok := d.Session(input.ChatID)
+	if !ok {
+		return nil
+	}
+	state, ok := d.states[session.state]
+	if !ok || state.OnText == nil {
+		return nil
+	}
+	e := newEvent(input, "", Params{}, session)
+
+	next, err := state.OnText(e)
+	if err != nil {
+		return fmt.Errorf("cannot handle text in state %s: %v", state.Name, err)
+	}
+	if next == "" {
+		return nil
+	}
+	if _, ok := d.states[next]; !ok {
+		return fmt.Errorf("text transition to unknown state %s", next)
+	}
+	return d.transit(e, next)
+}
+
+func (d *dialog[D]) Session(chatID int64) (*Session[D], bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	session := d.sessions.Get(chatID)
+	if session == nil {
+		return nil, false
+	}
+	// prolong session of active chat
+	d.sessions.Put(chatID, session)
+
+	return session, true
+}
+
+func (d *dialog[D]) Reset(chatID int64) {
+	d.sessions.Delete(chatID)
+}
+
+func (d *dialog[D]) Sweep() int {
+	return d.sessions.Sweep()
+}
+
+func (d *dialog[D]) session(chatID int64) (*Session[D], bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if session := d.sessions.Get(chatID); session != nil {
+		d.sessions.Put(chatID, session)
+		return session, false
+	}
+	session := &Session[D]{
+		ChatID: chatID,
+		Data:   d.newData(),
+	}
+	d.sessions.Put(chatID, session)
+
+	return session, true
+}
+
+func (d *dialog[D]) transition(from StateName, action Action) (Transition[D], bool) {
+	if state, ok := d.states[from]; ok {
+		if t, ok := state.Transitions[action]; ok {
+			return t, true
+		}
+	}
+	t, ok := d.config.Global[action]
+	return t, ok
+}
+
+// transit calls exit hook of current state and entry hook of next state.
+func (d *dialog[D]) transit(e *Event[D], to StateName) error {
+	session := e.Session
+
+	if state, ok := d.states[session.state]; ok && state.OnExit != nil {
+		if err := state.OnExit(e); err != nil {
+			return fmt.Errorf("cannot exit state %s: %v", state.Name, err)
+		}
+	}
+	state := d.states[to]
+	session.state = to
+
+	if state.OnEnter != nil {
+		if err := state.OnEnter(e); err != nil {
+			return fmt.Errorf("cannot enter state %s: %v", state.Name, err)
+		}
+	}
+	if state.Final {
+		d.Reset(session.ChatID)
+		return nil
+	}
+	// session may be stored as copy, so changed session is put again
+	d.sessions.Put(session.ChatID, session)
+
+	return nil
+}
+
+func (d *dialog[D]) pushHistory(session *Session[D]) {
+	session.history = append(session.history, session.state)
+
+	if len(session.history) > maxHistory {
+		session.history = session.history[len(session.history)-maxHistory:]
+	}
+}
+
+func (d *dialog[D]) newData() D {
+	if d.config.NewData == nil {
+		return *new(D)
+	}
+	return d.config.NewData()
+}
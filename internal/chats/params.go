@@ -0,0 +1,81 @@
+package chats
+
+import (
+	"fmt"
+	"main/pkg/http"
+	"main/pkg/str"
+	"net/url"
+	"strings"
+)
+
+// Action is command name without leading slash and query.
+type Action string
+
+// Params are command parameters with typed getters.
+type Params struct {
+	values url.Values
+}
+
+type Param struct {
+	key   string
+	value string
+}
+
+func StringParam(key, value string) Param {
+	return Param{
+		key:   key,
+		value: value,
+	}
+}
+
+func Int64Param(key string, value int64) Param {
+	return Param{
+		key:   key,
+		value: fmt.Sprint(value),
+	}
+}
+
+// NewCommand builds callback command for action with params, e.g. /unsub?id=1
+func NewCommand(action Action, params ...Param) string {
+	command := fmt.Sprint("/", action)
+
+	if len(params) == 0 {
+		return command
+	}
+	values := url.Values{}
+
+	for _, param := range params {
+		values.Set(param.key, param.value)
+	}
+	return fmt.Sprint(command, "?", values.Encode())
+}
+
+// ParseCommand splits command to action and params.
+func ParseCommand(command string) (Action, Params, error) {
+	action := Action(strings.TrimPrefix(http.TrimQuery(command), "/"))
+
+	if !http.HasQuery(command) {
+		return action, Params{}, nil
+	}
+	values, err := http.ParseQuery(command)
+	if err != nil {
+		return "", Params{}, fmt.Errorf("cannot parse command query: %v", err)
+	}
+	return action, Params{values: values}, nil
+}
+
+func (p Params) Has(key string) bool {
+	return p.values.Has(key)
+}
+
+func (p Params) String(key string) string {
+	return p.values.Get(key)
+}
+
+func (p Params) Int64(key string) (int64, error) {
+	value, err := str.Cast[int64](p.values.Get(key))
+	if err != nil {
+		return 0, fmt.Errorf("cannot cast param %s: %v", key, err)
+	}
+	return value, nil
+}
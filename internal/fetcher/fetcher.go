@@ -2,12 +2,23 @@ package fetcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"main/pkg/http"
+	"time"
 )
 
+// single vacancy is fetched for every tracked vacancy, so failed request is retried less than search
+const (
+	vacancyRetryCount = 3
+	vacancyRetryWait  = time.Second
+)
+
+var ErrVacancyNotFound = errors.New("vacancy not found")
+
 type Fetcher interface {
 	Fetch(context.Context, *Request) (*Response, error)
+	FetchVacancy(ctx context.Context, vacancyID string) (*VacancyResponseItem, error)
 }
 
 type fetcher struct {
@@ -44,3 +55,25 @@ func (f *fetcher) Fetch(ctx context.Context, req *Request) (*Response, error) {
 	}
 	return resp, nil
 }
+
+func (f *fetcher) FetchVacancy(ctx context.Context, vacancyID string) (*VacancyResponseItem, error) {
+	requestURL := vacancyRequestURL(vacancyID)
+
+	buf, err := f.client.Get(requestURL,
+		http.WithContext(ctx),
+		http.WithPrefix(f.proxy),
+		http.WithRetries(vacancyRetryCount, vacancyRetryWait),
+	)
+	if errors.Is(err, http.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrVacancyNotFound, vacancyID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get request to %s: %v", requestURL, err)
+	}
+	item := &VacancyResponseItem{}
+
+	if err = http.UnmarshalResponse(buf, item); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal vacancy response: %v", err)
+	}
+	return item, nil
+}
@@ -4,10 +4,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"main/pkg/http"
+	"net/url"
+	"time"
 )
 
 const vacanciesRequestURL = "https://api.hh.ru/vacancies"
 
+const (
+	vacancyAlternateURL = "https://hh.ru/vacancy/"
+	vacancyApplyURL     = "https://hh.ru/applicant/vacancy_response"
+)
+
+func vacancyRequestURL(vacancyID string) string {
+	return fmt.Sprint(vacanciesRequestURL, "/", url.PathEscape(vacancyID))
+}
+
+// VacancyAlternateURL returns hh.ru vacancy page url same as alternate_url field of response item.
+func VacancyAlternateURL(vacancyID string) string {
+	return fmt.Sprint(vacancyAlternateURL, url.PathEscape(vacancyID))
+}
+
+// VacancyApplyURL returns hh.ru vacancy response page url same as apply_alternate_url field of response item.
+func VacancyApplyURL(vacancyID string) string {
+	return fmt.Sprint(vacancyApplyURL, "?", url.Values{"vacancyId": {vacancyID}}.Encode())
+}
+
+// SearchPeriod is period of vacancies publication date in search requests
+const (
+	searchPeriodDays = 14
+	SearchPeriod     = searchPeriodDays * 24 * time.Hour
+)
+
 type Request struct {
 	Page        int    `json:"page,omitempty"`
 	PerPage     int    `json:"per_page,omitempty"`
@@ -24,7 +51,7 @@ type Request struct {
 
 func (r *Request) WithDefault() *Request {
 	r.SearchField = "name"
-	r.Period = 14
+	r.Period = searchPeriodDays
 	return r
 }
 
@@ -2,416 +2,387 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"main/internal/chats"
 	"main/internal/model"
+	"main/internal/storage"
+	"main/pkg/cache"
+	"main/pkg/hashring"
 	"main/pkg/http"
-	"main/pkg/str"
 	"main/pkg/telegram"
-	"main/pkg/tree"
 	"main/pkg/utils"
-	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func (h *Handler) setChatsTrees() {
-	h.chatsTrees = chats.NewChatsTrees(func() tree.Tree[chats.Link, *chats.State] {
-		root := tree.NewTree[chats.Link, *chats.State]()
+const (
+	stateStart          chats.StateName = "start"
+	stateStop           chats.StateName = "stop"
+	stateMan            chats.StateName = "man"
+	stateContacts       chats.StateName = "contacts"
+	stateSub            chats.StateName = "sub"
+	stateArea           chats.StateName = "area"
+	stateExperience     chats.StateName = "experience"
+	stateKeywords       chats.StateName = "keywords"
+	stateFillFields     chats.StateName = "fill"
+	stateConfirmCancel  chats.StateName = "confirmcancel"
+	stateConfirmed      chats.StateName = "confirmed"
+	stateCancelled      chats.StateName = "cancelled"
+	stateUnsub          chats.StateName = "unsub"
+	stateUnsubCompleted chats.StateName = "unsubcompleted"
+)
 
-		// node for /start
-		root.Push("start", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// push stop keyboard button if vacancies have been sent to chat id
-				withStop := h.chatsSentVacs.Exist(input.ChatID)
+const (
+	startAction      chats.Action = "start"
+	stopAction       chats.Action = "stop"
+	manAction        chats.Action = "man"
+	contactsAction   chats.Action = "contacts"
+	subAction        chats.Action = "sub"
+	unsubAction      chats.Action = "unsub"
+	areaAction       chats.Action = "area"
+	experienceAction chats.Action = "experience"
+	keywordsAction   chats.Action = "keywords"
+	confirmAction    chats.Action = "confirm"
+	cancelAction     chats.Action = "cancel"
+)
 
-				// if previous message id is set
-				if entity := root.Next("start").Entity(); entity != nil && entity.MessageID != 0 {
-					// edit previous message to start
-					messageID, err = h.bot.EditMessage(newStartMessage(input.ChatID, withStop).ToEditMessage(entity.MessageID))
-				} else {
-					// else send start message
-					messageID, err = h.bot.SendMessage(newStartMessage(input.ChatID, withStop))
-				}
-				if err != nil {
-					return 0, err
-				}
-				// put chat id to pending chats
-				h.chatsPending.Put(input.ChatID)
-				// return message id and error
-				return messageID, nil
-			},
-		})
+type chatEvent = chats.Event[*vacancy]
 
-		// go to child node /start
-		start := root.Next("start")
+type chatTransitions = map[chats.Action]chats.Transition[*vacancy]
 
-		// node for /man
-		start.Push("man", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := start.Entity().MessageID
-				// edit previous message to sub
-				return h.bot.EditMessage(newManMessage(input.ChatID).ToEditMessage(prevID))
-			},
-		})
+func (h *Handler) setChatsDialog() error {
+	menu := chatTransitions{
+		subAction:      {To: stateSub},
+		unsubAction:    {To: stateUnsub},
+		contactsAction: {To: stateContacts},
+		manAction:      {To: stateMan},
+	}
+	dialog, err := chats.NewDialog(chats.DialogConfig[*vacancy]{
+		NewData:     func() *vacancy { return &vacancy{} },
+		SessionTTL:  chatsStateTTL,
+		MaxSessions: chatsStateMaxSize,
+		Sessions: cache.NewBackendMemCache[int64, *chats.Session[*vacancy]](h.caches, "chats:sessions",
+			cache.WithTTL(chatsStateTTL),
+			cache.WithMaxSize(chatsStateMaxSize),
+		),
+		Global: chatTransitions{
+			startAction: {To: stateStart, Reset: true},
+			stopAction:  {To: stateStop},
+		},
+	},
+		&chats.State[*vacancy]{
+			Name: stateStart,
+			OnEnter: func(e *chatEvent) error {
+				// push stop keyboard button if vacancies have been sent to chat id
+				withStop := h.chatsWithSent.Exist(e.ChatID)
 
-		// node for /sub
-		start.Push("sub", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := start.Entity().MessageID
-				// edit previous message to sub
-				return h.bot.EditMessage(newSubMessage(input.ChatID).ToEditMessage(prevID))
+				if err := h.renderDialog(e, newStartMessage(e.ChatID, withStop)); err != nil {
+					return err
+				}
+				// put chat id to pending chats
+				h.chatsPending.Put(e.ChatID)
+				return nil
 			},
-		})
-
-		// node for /unsub
-		start.Push("unsub", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := start.Entity().MessageID
-
-				// try got sub id from query
-				if subID := http.MustParseQuery(input.Command).Get("id"); subID != "" {
-					// delete user subscription by id
-					if err := h.storage.DeleteChatSubscription(input.Ctx, str.MustCast[int64](subID)); err != nil {
-						return 0, err
+			Transitions: menu,
+		},
+		&chats.State[*vacancy]{
+			Name: stateStop,
+			OnEnter: func(e *chatEvent) error {
+				// delete previous sent message
+				if id := e.Session.MessageID; id != 0 {
+					if err := h.bot.DeleteMessage(e.ChatID, id); err != nil {
+						log.Infof("cannot delete telegram message: %v", err)
 					}
-					return h.bot.EditMessage(newUnsubCompleteMessage(input.ChatID).ToEditMessage(prevID))
 				}
-				// got subscriptions from storage for user
-				subs, err := h.storage.ChatSubscriptions(input.Ctx, input.ChatID)
-				if err != nil {
-					return 0, err
-				}
-				return h.bot.EditMessage(newUnsubMessage(input.ChatID, subs).ToEditMessage(prevID))
+				h.deleteChatState(e.ChatID)
+				return nil
 			},
-		})
-
-		// node for /contacts
-		start.Push("contacts", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := start.Entity().MessageID
-				// edit previous message to contacts
-				return h.bot.EditMessage(newContactsMessage(input.ChatID).ToEditMessage(prevID))
+			Final: true,
+		},
+		&chats.State[*vacancy]{
+			Name: stateMan,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newManMessage(e.ChatID))
 			},
-		})
-		// do not push node for /back
-
-		// go to child node /sub
-		sub := start.Next("sub")
-
-		// node for /area
-		sub.Push("area", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// try got area id from query
-				if areaID := http.MustParseQuery(input.Command).Get("id"); areaID != "" {
-					// set area id for user vacancy
-					subVac := h.chatsSubVacs.GetPut(input.ChatID, &vacancy{})
-					subVac.area = areaID
-
-					// got previous message id
-					prevID := sub.Entity().MessageID
-
-					// if sub vac completely filled
-					if subVac.IsFilled() {
-						// edit previous message to area
-						return h.bot.EditMessage(newConfirmCancelMessage(input.ChatID).ToEditMessage(prevID))
-					}
-					// else edit previous message to fill fields
-					return h.bot.EditMessage(newFillFieldsMessage(input.ChatID).ToEditMessage(prevID))
-				}
-				// got previous message id
-				prevID := sub.Entity().MessageID
-				// edit previous message to area
-				return h.bot.EditMessage(newAreaMessage(input.ChatID).ToEditMessage(prevID))
+			Transitions: menu,
+		},
+		&chats.State[*vacancy]{
+			Name: stateContacts,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newContactsMessage(e.ChatID))
 			},
-		})
-
-		// node for /experience
-		sub.Push("experience", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// try got area id from query
-				if experienceID := http.MustParseQuery(input.Command).Get("id"); experienceID != "" {
-					// set experience id for user vacancy
-					subVac := h.chatsSubVacs.GetPut(input.ChatID, &vacancy{})
-					subVac.experience = experienceID
-
-					// got previous message id
-					prevID := sub.Entity().MessageID
-
-					// if sub vac completely filled
-					if subVac.IsFilled() {
-						// edit previous message to area
-						return h.bot.EditMessage(newConfirmCancelMessage(input.ChatID).ToEditMessage(prevID))
-					}
-					// else edit previous message to fill fields
-					return h.bot.EditMessage(newFillFieldsMessage(input.ChatID).ToEditMessage(prevID))
-				}
-				// got previous message id
-				prevID := sub.Entity().MessageID
-				// edit previous message to area
-				return h.bot.EditMessage(newExperienceMessage(input.ChatID).ToEditMessage(prevID))
+		},
+		&chats.State[*vacancy]{
+			Name: stateSub,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newSubMessage(e.ChatID))
 			},
-		})
-
-		// node for /keywords
-		sub.Push("keywords", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := sub.Entity().MessageID
-				// edit previous message to area
-				return h.bot.EditMessage(newKeywordsMessage(input.ChatID).ToEditMessage(prevID))
+			Transitions: chatTransitions{
+				areaAction:       {To: stateArea},
+				experienceAction: {To: stateExperience},
+				keywordsAction:   {To: stateKeywords},
 			},
-		})
-
-		// go to child node /area
-		area := sub.Next("area")
-
-		// node for area /confirm
-		area.Push("confirm", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := sub.Entity().MessageID
-				// edit previous message to confirm
-				if messageID, err = h.bot.EditMessage(newConfirmMessage(input.ChatID).ToEditMessage(prevID)); err != nil {
-					return 0, err
-				}
-				// create new task for put subscription to storage
-				h.newTaskPutSubscription(input.UserID, input.ChatID)
-				// clear
-				h.deleteChatState(input.ChatID)
-
-				return messageID, nil
+		},
+		&chats.State[*vacancy]{
+			Name: stateArea,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newAreaMessage(e.ChatID))
 			},
-		})
-
-		// node for experience /cancel
-		area.Push("cancel", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := sub.Entity().MessageID
-				// edit previous message to area
-				if messageID, err = h.bot.EditMessage(newCancelMessage(input.ChatID).ToEditMessage(prevID)); err != nil {
-					return 0, err
-				}
-				return messageID, nil
+			Transitions: chatTransitions{
+				// chosen area replaces picker, so back returns to sub
+				areaAction: {
+					Do: func(e *chatEvent) (chats.StateName, error) {
+						if e.Session.Data.area = e.Params.String("id"); e.Session.Data.area == "" {
+							return stateArea, nil
+						}
+						return filledState(e.Session.Data), nil
+					},
+					Replace: true,
+				},
 			},
-		})
-
-		// go to child node /experience
-		experience := sub.Next("experience")
-
-		// node for experience /confirm
-		experience.Push("confirm", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := sub.Entity().MessageID
-				// edit previous message to confirm
-				if messageID, err = h.bot.EditMessage(newConfirmMessage(input.ChatID).ToEditMessage(prevID)); err != nil {
-					return 0, err
+		},
+		&chats.State[*vacancy]{
+			Name: stateExperience,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newExperienceMessage(e.ChatID))
+			},
+			Transitions: chatTransitions{
+				experienceAction: {
+					Do: func(e *chatEvent) (chats.StateName, error) {
+						if e.Session.Data.experience = e.Params.String("id"); e.Session.Data.experience == "" {
+							return stateExperience, nil
+						}
+						return filledState(e.Session.Data), nil
+					},
+					Replace: true,
+				},
+			},
+		},
+		&chats.State[*vacancy]{
+			Name: stateKeywords,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newKeywordsMessage(e.ChatID))
+			},
+			OnText: func(e *chatEvent) (chats.StateName, error) {
+				e.Session.Data.keywords = e.Text
+				return filledState(e.Session.Data), nil
+			},
+		},
+		&chats.State[*vacancy]{
+			Name: stateFillFields,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newFillFieldsMessage(e.ChatID))
+			},
+		},
+		&chats.State[*vacancy]{
+			Name: stateConfirmCancel,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newConfirmCancelMessage(e.ChatID))
+			},
+			Transitions: chatTransitions{
+				confirmAction: {
+					Do: func(e *chatEvent) (chats.StateName, error) {
+						e.Session.Data.urgent = e.Params.Has("urgent")
+						return stateConfirmed, nil
+					},
+				},
+				cancelAction: {To: stateCancelled},
+			},
+		},
+		&chats.State[*vacancy]{
+			Name: stateConfirmed,
+			OnEnter: func(e *chatEvent) error {
+				if err := h.renderDialog(e, newConfirmMessage(e.ChatID)); err != nil {
+					return err
 				}
 				// create new task for put subscription to storage
-				h.newTaskPutSubscription(input.UserID, input.ChatID)
-				// create new chat tree for chat id
-				h.chatsTrees.RebuildTree(input.ChatID)
+				h.newTaskPutSubscription(e.UserID, e.ChatID, e.Session.Data)
+				h.answerCallback(e.CallbackID, telegram.WithToast("Подписка создана ✅"))
+				h.deleteChatState(e.ChatID)
 
-				return messageID, nil
+				return nil
 			},
-		})
+			Final: true,
+		},
+		&chats.State[*vacancy]{
+			Name: stateCancelled,
+			OnEnter: func(e *chatEvent) error {
+				if err := h.renderDialog(e, newCancelMessage(e.ChatID)); err != nil {
+					return err
+				}
+				h.answerCallback(e.CallbackID, telegram.WithToast("Создание подписки отменено ❗"))
+				h.deleteChatState(e.ChatID)
 
-		// node for experience /cancel
-		experience.Push("cancel", &chats.State{
-			Event: func(input *chats.EventInput) (messageID int64, err error) {
-				// got previous message id
-				prevID := sub.Entity().MessageID
-				// edit previous message to area
-				if messageID, err = h.bot.EditMessage(newCancelMessage(input.ChatID).ToEditMessage(prevID)); err != nil {
-					return 0, err
+				return nil
+			},
+			Final: true,
+		},
+		&chats.State[*vacancy]{
+			Name: stateUnsub,
+			OnEnter: func(e *chatEvent) error {
+				// got subscriptions from storage for user
+				subs, err := h.storage.ChatSubscriptions(e.Ctx, e.ChatID)
+				if err != nil {
+					return err
 				}
-				return messageID, nil
+				return h.renderDialog(e, newUnsubMessage(e.ChatID, subs))
+			},
+			Transitions: chatTransitions{
+				unsubAction: {
+					To: stateUnsubCompleted,
+					Do: func(e *chatEvent) (chats.StateName, error) {
+						if !e.Params.Has("id") {
+							return stateUnsub, nil
+						}
+						subID, err := e.Params.Int64("id")
+						if err != nil {
+							return "", err
+						}
+						// delete user subscription by id
+						if err = h.storage.DeleteChatSubscription(e.Ctx, e.ChatID, subID); err != nil {
+							// subscription already deleted
+							if !errors.Is(err, storage.ErrSubscriptionNotFound) {
+								return "", err
+							}
+						}
+						h.publishSubscriptionChanged(e.Ctx, &subscriptionEvent{
+							ChatID:         e.ChatID,
+							SubscriptionID: subID,
+							Deleted:        true,
+						})
+						h.answerCallback(e.CallbackID, telegram.WithToast("Подписка удалена ✅"))
+						return "", nil
+					},
+					Replace: true,
+				},
 			},
-		})
+		},
+		&chats.State[*vacancy]{
+			Name: stateUnsubCompleted,
+			OnEnter: func(e *chatEvent) error {
+				return h.renderDialog(e, newUnsubCompleteMessage(e.ChatID))
+			},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("cannot create chats dialog: %v", err)
+	}
+	h.chatsDialog = dialog
 
-		// return root as full pushed chat tree
-		return root
-	})
+	return nil
 }
 
-func (h *Handler) HandleMessages(ctx context.Context, m *telegram.Message) error {
-	// handle text messages
-	if m.IsText() {
-		chatTree := h.chatsTrees.Tree(m.ChatID)
-
-		if link := chatTree.Link(); link == "keywords" {
-			// set experience id for user vacancy
-			subVac := h.chatsSubVacs.GetPut(m.ChatID, &vacancy{})
-			subVac.keywords = m.Text
-
-			if entity := chatTree.Entity(); entity != nil {
-				// got previous message id
-				prevID := entity.MessageID
-
-				// if user vacancy completely filled
-				if subVac.IsFilled() {
-					// edit previous message to confirm
-					messageID, err := h.bot.EditMessage(newConfirmCancelMessage(m.ChatID).ToEditMessage(prevID))
-					if err != nil {
-						return err
-					}
-					entity.MessageID = messageID
-
-					// push node for /confirm
-					chatTree.Push("confirm", &chats.State{
-						Event: func(input *chats.EventInput) (messageID int64, err error) {
-							// edit previous message to confirm
-							if messageID, err = h.bot.EditMessage(newConfirmMessage(input.ChatID).ToEditMessage(prevID)); err != nil {
-								return 0, err
-							}
-							// create new task for put subscription to storage
-							h.newTaskPutSubscription(input.UserID, input.ChatID)
+// filledState returns confirmation state if subscription vacancy completely filled.
+func filledState(v *vacancy) chats.StateName {
+	if v.IsFilled() {
+		return stateConfirmCancel
+	}
+	return stateFillFields
+}
 
-							return messageID, nil
-						},
-					})
+// renderDialog edits dialog message of chat session or sends new one.
+func (h *Handler) renderDialog(e *chatEvent, msg *telegram.SendMessage) error {
+	if id := e.Session.MessageID; id != 0 {
+		if _, err := h.bot.EditMessage(msg.ToEditMessage(id)); err != nil {
+			return fmt.Errorf("cannot edit dialog message: %v", err)
+		}
+		return nil
+	}
+	id, err := h.bot.SendMessage(msg)
+	if err != nil {
+		return fmt.Errorf("cannot send dialog message: %v", err)
+	}
+	e.Session.MessageID = id
 
-					// push node for /cancel
-					chatTree.Push("cancel", &chats.State{
-						Event: func(input *chats.EventInput) (messageID int64, err error) {
-							// edit previous message to area
-							if messageID, err = h.bot.EditMessage(newCancelMessage(input.ChatID).ToEditMessage(prevID)); err != nil {
-								return 0, err
-							}
-							return messageID, nil
-						},
-					})
+	return nil
+}
 
-					return nil
-				}
-				// else edit message to fill fields
-				messageID, err := h.bot.EditMessage(newFillFieldsMessage(m.ChatID).ToEditMessage(prevID))
-				if err != nil {
-					return err
-				}
-				entity.MessageID = messageID
-			}
+func (h *Handler) HandleMessages(ctx context.Context, m *telegram.Message) error {
+	// callback data cannot be decoded, e.g. pressed button is expired or forged
+	if err := m.CallbackError(); err != nil {
+		if errors.Is(err, telegram.ErrCallbackInvalid) {
+			log.Warnf("invalid callback data for chat with id %d: %v", m.ChatID, err)
+		} else {
+			log.Infof("cannot decode callback data for chat with id %d: %v", m.ChatID, err)
 		}
+		h.answerCallback(m.CallbackID, telegram.WithAlert("Кнопка устарела, откройте меню бота заново ❗"))
+
 		return nil
 	}
+	input := &chats.EventInput{
+		Ctx:        ctx,
+		UserID:     m.UserID,
+		ChatID:     m.ChatID,
+		Text:       m.Text,
+		Command:    m.Command,
+		CallbackID: m.CallbackID,
+	}
+	// handle text messages
+	if m.IsText() {
+		return h.chatsDialog.HandleText(input)
+	}
 	// handle command messages
 	if m.IsCommand() {
-		link := chats.Link(m.Command)
+		link := http.TrimQuery(m.Command)
 
 		// if command not from callback query
-		if !m.FromCallback() && link != "start" {
+		if !m.FromCallback() && link != string(startAction) && link != favoritesLink {
 			// not handle user entered commands
 			return nil
 		}
-		chatTree := h.chatsTrees.Tree(m.ChatID)
-
-		defer func() {
-			// if link it /confirm, /cancel, /stop
-			if str.OneOf(func(s string) bool {
-				return s == string(link)
-			}, "confirm", "cancel", "stop") {
-				// if link it /stop
-				if link == "stop" {
-					prevID := h.chatsTrees.Tree(m.ChatID).Entity().MessageID
-
-					// delete previous sent message
-					if err := h.bot.DeleteMessage(m.ChatID, prevID); err != nil {
-						log.Infof("cannot delete telegram message: %v", err)
-						return
-					}
-				}
-				// delete chat state
-				h.deleteChatState(m.ChatID)
-				// create new chat tree for chat id
-				h.chatsTrees.RebuildTree(m.ChatID)
-			}
-		}()
-
-		// handle /back
-		if link == "back" {
-			chatTree = chatTree.Prev()
-
-			if entity := chatTree.Entity(); entity != nil {
-				messageID, err := entity.Event(&chats.EventInput{
-					Ctx:     ctx,
-					UserID:  m.UserID,
-					ChatID:  m.ChatID,
-					Text:    m.Text,
-					Command: m.Command,
-				})
-				if err != nil {
-					return err
-				}
-				h.chatsTrees.SetTree(m.ChatID, chatTree)
-
-				entity.MessageID = messageID
+		// reactivate subscriptions if chat returned to bot
+		if link == string(startAction) && !m.FromCallback() {
+			if err := h.storage.SetChatSubscriptionsActive(ctx, m.ChatID, true); err != nil {
+				return fmt.Errorf("cannot activate chat subscriptions in storage: %v", err)
 			}
-			return nil
+			h.publishSubscriptionChanged(ctx, &subscriptionEvent{ChatID: m.ChatID})
 		}
-
-		// if link it /area, /experience, /sub
-		if str.OneOf(func(s string) bool {
-			return strings.HasPrefix(string(link), s)
-		}, "area", "experience", "unsub") {
-
-			// if link has query suffix
-			if http.HasQuery(string(link)) {
-				chatTree = chatTree.Prev()
-			}
-			// trim query suffixes for /area and /experience links
-			link = chats.Link(http.TrimQuery(string(link)))
-		}
-		chatTree = chatTree.Next(link)
-
-		if entity := chatTree.Entity(); entity != nil {
-			messageID, err := entity.Event(&chats.EventInput{
-				Ctx:     ctx,
-				UserID:  m.UserID,
-				ChatID:  m.ChatID,
-				Text:    m.Text,
-				Command: m.Command,
-			})
-			if err != nil {
-				return err
-			}
-			entity.MessageID = messageID
-			h.chatsTrees.SetTree(m.ChatID, chatTree)
+		// handle vacancy messages and favorites outside chats dialog
+		switch link {
+		case reactionLink:
+			return h.handleVacancyReaction(ctx, m)
+		case saveLink:
+			return h.handleVacancySave(ctx, m)
+		case favoritesLink, favoriteLink, favoriteDeleteLink, favoriteExportLink:
+			return h.handleFavorites(ctx, m)
 		}
+		return h.chatsDialog.HandleCommand(input)
 	}
 
 	return nil
 }
 
-func (h *Handler) newTaskPutSubscription(userID, chatID int64) {
-	// got subscription vacancy for user
-	subVac := h.chatsSubVacs.GetPut(chatID, &vacancy{})
-
+func (h *Handler) newTaskPutSubscription(userID, chatID int64, subVac *vacancy) {
 	// push task to queue
-	h.subTasks.Push(func() error {
-		if err := h.storage.PutChatSubscription(h.ctx, &model.ChatSubscription{
+	if err := h.subTasks.Push(func(ctx context.Context) error {
+		now := utils.NowTimeUTC()
+
+		sub := &model.ChatSubscription{
 			ChatID:     chatID,
 			UserID:     userID,
 			Keywords:   subVac.keywords,
 			Area:       subVac.area,
 			Experience: subVac.experience,
-			CreatedAt:  utils.NowTimeUTC(),
-		}); err != nil {
+			Urgent:     subVac.urgent,
+			// new subscription is polled right away
+			NextPollAt: now,
+			CreatedAt:  now,
+		}
+		sub.PollHash = hashring.Hash(pollGroupKey(sub))
+
+		if err := h.storage.PutChatSubscription(ctx, sub); err != nil {
 			return fmt.Errorf("cannot put subscription in storage: %v", err)
 		}
+		h.publishSubscriptionChanged(ctx, &subscriptionEvent{ChatID: chatID})
+
 		return nil
-	})
+	}); err != nil {
+		log.Errorf("cannot push put subscription task for chat with id %d: %v", chatID, err)
+	}
 }
 
 func (h *Handler) deleteChatState(chatID int64) {
 	// remove chat from
 	h.chatsPending.Delete(chatID)
-
-	// delete subscription vacancy for user
-	h.chatsSubVacs.Delete(chatID)
 }
@@ -0,0 +1,42 @@
+package handler
+
+import "main/internal/maintenance"
+
+const defaultSentBloomFalsePositive = 0.01
+
+type Config struct {
+	SentVacancies *SentVacanciesConfig `yaml:"sent_vacancies"`
+	Maintenance   *maintenance.Config  `yaml:"maintenance"`
+}
+
+type SentVacanciesConfig struct {
+	// BloomCapacity enables per chat bloom filters of sent vacancies ids sized for capacity ids
+	BloomCapacity      int     `yaml:"bloom_capacity"`
+	BloomFalsePositive float64 `yaml:"bloom_false_positive"`
+}
+
+func (c *Config) withDefault() *Config {
+	config := &Config{
+		SentVacancies: &SentVacanciesConfig{
+			BloomFalsePositive: defaultSentBloomFalsePositive,
+		},
+	}
+	if c == nil {
+		return config
+	}
+	// maintenance defaults are set by maintainer
+	config.Maintenance = c.Maintenance
+
+	if c.SentVacancies == nil {
+		return config
+	}
+	sent := c.SentVacancies
+
+	if sent.BloomCapacity > 0 {
+		config.SentVacancies.BloomCapacity = sent.BloomCapacity
+	}
+	if sent.BloomFalsePositive > 0 {
+		config.SentVacancies.BloomFalsePositive = sent.BloomFalsePositive
+	}
+	return config
+}
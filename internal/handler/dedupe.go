@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"main/pkg/cache"
+	"time"
+)
+
+const (
+	sentIDsBatchSize = 500
+	// bloom filters are rebuilt from storage, so pruned and other instances sent vacancies are dropped
+	sentBloomTTL     = 24 * time.Hour
+	sentBloomMaxSize = 10_000
+)
+
+func (h *Handler) setChatsSentBlooms() {
+	if h.config.SentVacancies.BloomCapacity == 0 {
+		return
+	}
+	// filters are local short-circuit and not shared between instances
+	h.chatsSentBlooms = cache.NewMemCache[int64, cache.BloomFilter](
+		cache.WithTTL(sentBloomTTL),
+		cache.WithMaxSize(sentBloomMaxSize),
+	)
+}
+
+// chatSentVacancies returns set of passed vacancy ids which were already sent or claimed for chat.
+// Ids which are definitely not sent by chat bloom filter are not queried from storage.
+func (h *Handler) chatSentVacancies(ctx context.Context, chatID int64, ids []string) (map[string]struct{}, error) {
+	check := ids
+
+	if h.chatsSentBlooms != nil {
+		bloom, err := h.chatSentBloom(ctx, chatID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot got chat sent vacancies bloom filter: %v", err)
+		}
+		check = make([]string, 0, len(ids))
+
+		for _, id := range ids {
+			if bloom.MayContain(id) {
+				check = append(check, id)
+			}
+		}
+	}
+	sent := make(map[string]struct{}, len(check))
+
+	for start := 0; start < len(check); start += sentIDsBatchSize {
+		end := start + sentIDsBatchSize
+
+		if end > len(check) {
+			end = len(check)
+		}
+		sentIDs, err := h.storage.SentVacancyIDs(ctx, chatID, check[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("cannot got sent vacancy ids from storage: %v", err)
+		}
+		for _, id := range sentIDs {
+			sent[id] = struct{}{}
+		}
+	}
+	return sent, nil
+}
+
+// chatSentBloom returns bloom filter of chat built from last sent vacancies.
+// Vacancies missed in filter, e.g. older than capacity, are claimed again and skipped by storage.
+func (h *Handler) chatSentBloom(ctx context.Context, chatID int64) (cache.BloomFilter, error) {
+	if bloom := h.chatsSentBlooms.Get(chatID); bloom != nil && !bloom.Saturated() {
+		return bloom, nil
+	}
+	config := h.config.SentVacancies
+
+	ids, err := h.storage.ChatSentVacancyIDs(ctx, chatID, int64(config.BloomCapacity))
+	if err != nil {
+		return nil, fmt.Errorf("cannot got chat sent vacancy ids from storage: %v", err)
+	}
+	bloom := cache.NewBloomFilter(config.BloomCapacity, config.BloomFalsePositive)
+
+	for _, id := range ids {
+		bloom.Add(id)
+	}
+	h.chatsSentBlooms.Put(chatID, bloom)
+
+	return bloom, nil
+}
+
+func (h *Handler) putChatSentVacancy(chatID int64, vacancyID string) {
+	if h.chatsSentBlooms != nil {
+		if bloom := h.chatsSentBlooms.Get(chatID); bloom != nil {
+			bloom.Add(vacancyID)
+		}
+	}
+	// prolong chat with last sent vacancy
+	h.chatsWithSent.Put(chatID)
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"main/internal/fetcher"
+	"main/internal/model"
+	"main/pkg/telegram"
+	"main/pkg/utils"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	deliveryMaxAttempts  = 5
+	deliveryBatchSize    = 100
+	deliveryVisibility   = 5 * time.Minute
+	deliveryPollInterval = 5 * time.Second
+	deliveryPendingDelay = 1 * time.Minute
+	deliveryRetryWait    = 30 * time.Second
+	deliveryMaxRetryWait = 30 * time.Minute
+	// lease of jobs in flight is extended few times per visibility timeout, e.g. while sends wait chat pacing
+	deliveryLeaseExtend = deliveryVisibility / 3
+)
+
+// deliveryPayload keeps everything needed to render vacancy message after restart.
+type deliveryPayload struct {
+	Keywords string                       `json:"keywords"`
+	Item     *fetcher.VacancyResponseItem `json:"item"`
+}
+
+func newDeliveryJob(s *model.ChatSubscription, item *fetcher.VacancyResponseItem) (*model.DeliveryJob, error) {
+	payload, err := json.Marshal(&deliveryPayload{
+		Keywords: s.Keywords,
+		Item:     item,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal delivery payload: %v", err)
+	}
+	now := utils.NowTimeUTC()
+
+	return &model.DeliveryJob{
+		SubscriptionID: s.SubscriptionID,
+		ChatID:         s.ChatID,
+		VacancyID:      item.Id,
+		Payload:        payload,
+		Status:         model.DeliveryJobPending,
+		MaxAttempts:    deliveryMaxAttempts,
+		AvailableAt:    now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// HandleDeliveries leases batch of delivery jobs and sends them. Returns count of leased jobs.
+func (h *Handler) HandleDeliveries(ctx context.Context) (int, error) {
+	jobs, err := h.storage.LeaseDeliveryJobs(ctx, deliveryBatchSize, utils.NowTimeUTC().Add(deliveryVisibility))
+	if err != nil {
+		return 0, fmt.Errorf("cannot lease delivery jobs from storage: %v", err)
+	}
+	var (
+		wg       = sync.WaitGroup{}
+		inflight = newInflightJobs(jobs)
+		stop     = make(chan struct{})
+	)
+	go h.extendDeliveryLeases(ctx, inflight, stop)
+	defer close(stop)
+
+	for _, job := range jobs {
+		job := job
+		wg.Add(1)
+
+		if err = h.sendTasks.Push(func(ctx context.Context) error {
+			defer wg.Done()
+			defer inflight.done(job.JobID)
+
+			return h.deliverJob(ctx, job)
+
+		}); err != nil {
+			// job will be leased again after visibility timeout
+			wg.Done()
+			inflight.done(job.JobID)
+			log.Errorf("cannot push delivery job %d send task: %v", job.JobID, err)
+		}
+	}
+	// wait batch before next lease, leases are extended until jobs are finished
+	wg.Wait()
+
+	return len(jobs), nil
+}
+
+// extendDeliveryLeases prolongs leases of jobs in flight until stopped,
+// so jobs waiting in send queue are not leased and sent by other instance.
+func (h *Handler) extendDeliveryLeases(ctx context.Context, inflight *inflightJobs, stop <-chan struct{}) {
+	ticker := time.NewTicker(deliveryLeaseExtend)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		jobIDs := inflight.ids()
+
+		if len(jobIDs) == 0 {
+			continue
+		}
+		if err := h.storage.ExtendDeliveryJobsLease(ctx, jobIDs, utils.NowTimeUTC().Add(deliveryVisibility)); err != nil {
+			log.Errorf("cannot extend delivery jobs lease in storage: %v", err)
+		}
+	}
+}
+
+func (h *Handler) HandleDeliveriesContinuously(ctx context.Context) {
+	for ctx.Err() == nil {
+		count, err := h.HandleDeliveries(ctx)
+		if err != nil {
+			log.Errorf("cannot handle delivery jobs: %v", err)
+		}
+		if count > 0 && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(deliveryPollInterval):
+		}
+	}
+}
+
+func (h *Handler) deliverJob(ctx context.Context, job *model.DeliveryJob) error {
+	// lease expired too many times, e.g. worker crashed while sending
+	if job.Attempts > job.MaxAttempts {
+		return h.storage.DeadDeliveryJob(ctx, job.JobID, "delivery attempts exhausted")
+	}
+	// do not send vacancies while chat is in bot menu
+	if h.chatsPending.Exist(job.ChatID) {
+		return h.storage.PostponeDeliveryJob(ctx, job.JobID, utils.NowTimeUTC().Add(deliveryPendingDelay))
+	}
+	// vacancy delivered before, e.g. instance stopped after sending, so job is completed without sending again
+	status, sentMessageID, err := h.storage.SentVacancyStatus(ctx, job.SentID)
+	if err != nil {
+		return fmt.Errorf("cannot got sent vacancy status from storage: %v", err)
+	}
+	if status == model.SentVacancySent || sentMessageID != 0 {
+		// only pending vacancy updated, so sent message id is kept and job completed
+		return h.storage.MarkVacancySent(ctx, job, sentMessageID)
+	}
+	payload := &deliveryPayload{}
+
+	if err := json.Unmarshal(job.Payload, payload); err != nil || payload.Item == nil {
+		return h.storage.DeadDeliveryJob(ctx, job.JobID, fmt.Sprintf("cannot unmarshal delivery payload: %v", err))
+	}
+	sub := &model.ChatSubscription{
+		SubscriptionID: job.SubscriptionID,
+		ChatID:         job.ChatID,
+		Keywords:       payload.Keywords,
+	}
+	messageID, err := h.bot.SendMessage(newVacancyMessage(sub, payload.Item))
+	if err != nil {
+		// chat unavailable or migrated
+		if handled, handleErr := h.handleChatError(ctx, job.ChatID, err); handled {
+			if handleErr != nil {
+				return h.failDeliveryJob(ctx, job, handleErr)
+			}
+			if _, migrated := telegram.AsChatMigrated(err); migrated {
+				// job chat id updated by migration, so retry immediately
+				return h.storage.PostponeDeliveryJob(ctx, job.JobID, utils.NowTimeUTC())
+			}
+			return h.storage.DeadDeliveryJob(ctx, job.JobID, err.Error())
+		}
+		return h.failDeliveryJob(ctx, job, fmt.Errorf("cannot send vacancy telegram bot message: %v", err))
+	}
+	return h.markVacancySent(ctx, job, messageID)
+}
+
+// markVacancySent retries marking delivered vacancy while job lease is extended,
+// so vacancy is sent twice only if instance stops between sending and marking.
+func (h *Handler) markVacancySent(ctx context.Context, job *model.DeliveryJob, messageID int64) error {
+	for {
+		err := h.storage.MarkVacancySent(ctx, job, messageID)
+		if err == nil {
+			return nil
+		}
+		log.Errorf("cannot mark vacancy sent in storage: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cannot mark vacancy sent in storage: %v", err)
+		case <-time.After(deliveryRetryWait):
+		}
+	}
+}
+
+func (h *Handler) failDeliveryJob(ctx context.Context, job *model.DeliveryJob, reason error) error {
+	wait := deliveryRetryWait << (job.Attempts - 1)
+
+	if wait <= 0 || wait > deliveryMaxRetryWait {
+		wait = deliveryMaxRetryWait
+	}
+	if err := h.storage.FailDeliveryJob(ctx, job.JobID, utils.NowTimeUTC().Add(wait), reason.Error()); err != nil {
+		return fmt.Errorf("cannot fail delivery job in storage: %v", err)
+	}
+	return reason
+}
+
+// inflightJobs tracks leased jobs which are not finished yet.
+type inflightJobs struct {
+	mutex sync.Mutex
+	jobs  map[int64]struct{}
+}
+
+func newInflightJobs(jobs []*model.DeliveryJob) *inflightJobs {
+	inflight := &inflightJobs{
+		jobs: make(map[int64]struct{}, len(jobs)),
+	}
+	for _, job := range jobs {
+		inflight.jobs[job.JobID] = struct{}{}
+	}
+	return inflight
+}
+
+func (i *inflightJobs) done(jobID int64) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	delete(i.jobs, jobID)
+}
+
+func (i *inflightJobs) ids() []int64 {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	ids := make([]int64, 0, len(i.jobs))
+
+	for jobID := range i.jobs {
+		ids = append(ids, jobID)
+	}
+	return ids
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"main/pkg/telegram"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// handleChatError deactivates subscriptions of unavailable chat or moves chat state to migrated supergroup.
+// Reports whether the error was handled and sending to chat should be stopped.
+func (h *Handler) handleChatError(ctx context.Context, chatID int64, err error) (bool, error) {
+	if migrated, ok := telegram.AsChatMigrated(err); ok {
+		newChatID := migrated.MigrateToChatID
+
+		if err = h.storage.MigrateChat(ctx, chatID, newChatID); err != nil {
+			return true, fmt.Errorf("cannot migrate chat in storage: %v", err)
+		}
+		// sent vacancies of old chat id are not copied, claims in storage are bound to subscriptions and skip them
+		if h.chatsWithSent.Exist(chatID) {
+			h.chatsWithSent.Put(newChatID)
+			h.chatsWithSent.Delete(chatID)
+		}
+		log.Infof("chat with id %d migrated to supergroup chat with id %d", chatID, newChatID)
+		return true, nil
+	}
+	if telegram.IsChatUnavailable(err) {
+		if err = h.storage.SetChatSubscriptionsActive(ctx, chatID, false); err != nil {
+			return true, fmt.Errorf("cannot deactivate chat subscriptions in storage: %v", err)
+		}
+		log.Infof("chat with id %d unavailable. subscriptions deactivated: %v", chatID, err)
+		return true, nil
+	}
+	return false, nil
+}
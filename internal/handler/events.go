@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/pkg/redis"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	subscriptionsChannel = "events:subscriptions"
+	eventsRetryWait      = 3 * time.Second
+
+	// chat lock lease is extended while update is handled
+	chatLockTTL        = 30 * time.Second
+	chatLockRetryCount = 100
+	chatLockRetryWait  = 100 * time.Millisecond
+	chatUnlockTimeout  = time.Second
+)
+
+// subscriptionEvent is broadcast to instances when chat subscriptions changed.
+type subscriptionEvent struct {
+	ChatID         int64 `json:"chat_id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	Deleted        bool  `json:"deleted"`
+}
+
+func (h *Handler) setEvents() {
+	if !h.caches.Shared() {
+		return
+	}
+	h.locker = redis.NewLocker(h.caches.Client())
+	h.events = redis.NewPubSub(h.caches.Client())
+}
+
+// publishSubscriptionChanged notifies instances that chat subscriptions changed, so owner shard polls them without delay.
+func (h *Handler) publishSubscriptionChanged(ctx context.Context, event *subscriptionEvent) {
+	if h.events == nil {
+		h.handleSubscriptionChanged(event)
+		return
+	}
+	if err := h.events.Publish(ctx, h.caches.Name(subscriptionsChannel), event); err != nil {
+		log.Warnf("cannot publish subscription event for chat with id %d: %v", event.ChatID, err)
+	}
+}
+
+func (h *Handler) handleSubscriptionChanged(event *subscriptionEvent) {
+	if event.Deleted {
+		h.polls.deleteSubscription(event.SubscriptionID)
+		return
+	}
+	h.polls.resetRefresh()
+}
+
+// HandleEventsContinuously receives events broadcast by instances.
+func (h *Handler) HandleEventsContinuously(ctx context.Context) {
+	if h.events == nil {
+		return
+	}
+	channel := h.caches.Name(subscriptionsChannel)
+
+	for {
+		if err := h.events.Subscribe(ctx, func(m *redis.Message) {
+			event := &subscriptionEvent{}
+
+			if err := m.Decode(event); err != nil {
+				log.Warnf("cannot decode subscription event: %v", err)
+				return
+			}
+			h.handleSubscriptionChanged(event)
+		}, channel); err != nil {
+			log.Errorf("cannot subscribe to events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventsRetryWait):
+		}
+	}
+}
+
+// lockChat serializes chat updates handling between instances and returns unlock func.
+// Updates are serialized by chat workers inside instance, so lock is not taken for memory caches.
+func (h *Handler) lockChat(ctx context.Context, chatID int64) (func(), error) {
+	if h.locker == nil {
+		return func() {}, nil
+	}
+	key := h.caches.Name(fmt.Sprintf("chats:lock:%d", chatID))
+
+	lock, err := h.locker.Obtain(ctx, key, chatLockTTL, redis.WithLockRetry(chatLockRetryCount, chatLockRetryWait))
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain chat lock: %v", err)
+	}
+	done := make(chan struct{})
+
+	// extend lease of long handled update
+	go func() {
+		ticker := time.NewTicker(chatLockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := lock.Extend(ctx, chatLockTTL); err != nil {
+					log.Warnf("cannot extend chat with id %d lock: %v", chatID, err)
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+
+		ctx, cancel := context.WithTimeout(context.Background(), chatUnlockTimeout)
+		defer cancel()
+
+		if err := lock.Release(ctx); err != nil && !errors.Is(err, redis.ErrLockNotHeld) {
+			log.Warnf("cannot release chat with id %d lock: %v", chatID, err)
+		}
+	}, nil
+}
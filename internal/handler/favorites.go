@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"main/internal/fetcher"
+	"main/internal/model"
+	"main/pkg/http"
+	"main/pkg/str"
+	"main/pkg/telegram"
+	"main/pkg/utils"
+)
+
+const (
+	favoritesLink      = "favorites"
+	favoriteLink       = "favorite"
+	favoriteDeleteLink = "favdelete"
+	favoriteExportLink = "favexport"
+
+	favoritesPerPage = 5
+)
+
+func favoritesCommand(page int64) string {
+	return fmt.Sprintf("/%s?p=%d", favoritesLink, page)
+}
+
+func favoriteCommand(link string, favID, page int64) string {
+	return fmt.Sprintf("/%s?id=%d&p=%d", link, favID, page)
+}
+
+func newFavoriteVacancy(chatID int64, item *fetcher.VacancyResponseItem) *model.ChatFavoriteVacancy {
+	now := utils.NowTimeUTC()
+
+	fav := &model.ChatFavoriteVacancy{
+		ChatID:    chatID,
+		VacancyID: item.Id,
+		Name:      item.Name,
+		Url:       item.AlternateUrl,
+		Archived:  item.Archived,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if employer := item.Employer; employer != nil {
+		fav.EmployerID = employer.Id
+		fav.EmployerName = employer.Name
+	}
+	if area := item.Area; area != nil {
+		fav.Area = area.Name
+	}
+	if salary := item.Salary; salary != nil {
+		fav.SalaryFrom = int64(salary.From)
+		fav.SalaryTo = int64(salary.To)
+		fav.SalaryCurrency = salary.Currency
+		fav.SalaryGross = salary.Gross
+	}
+	return fav
+}
+
+func (h *Handler) handleVacancySave(ctx context.Context, m *telegram.Message) error {
+	r, err := parseVacancyReaction(m.Command)
+	if err != nil {
+		return fmt.Errorf("cannot parse vacancy reaction: %v", err)
+	}
+	// fetch actual vacancy fields for snapshot
+	item, err := h.fetcher.FetchVacancy(ctx, r.vacancyID)
+	if err != nil {
+		return fmt.Errorf("cannot fetch vacancy: %v", err)
+	}
+	if err = h.storage.PutFavoriteVacancy(ctx, newFavoriteVacancy(m.ChatID, item)); err != nil {
+		return fmt.Errorf("cannot put favorite vacancy to storage: %v", err)
+	}
+	// keep chosen reaction on vacancy message keyboard
+	if r.reaction, err = h.storage.VacancyReaction(ctx, m.ChatID, r.vacancyID); err != nil {
+		return fmt.Errorf("cannot got vacancy reaction from storage: %v", err)
+	}
+	r.saved = true
+
+	if err = h.bot.EditKeyboard(m.ChatID, m.MessageID, newVacancyKeyboard(r)); err != nil {
+		return fmt.Errorf("cannot edit vacancy message keyboard: %v", err)
+	}
+	h.answerCallback(m.CallbackID, telegram.WithToast("Вакансия сохранена в избранное ⭐"))
+
+	return nil
+}
+
+func (h *Handler) handleFavorites(ctx context.Context, m *telegram.Message) error {
+	link := http.TrimQuery(m.Command)
+
+	query, err := http.ParseQuery(m.Command)
+	if err != nil {
+		return fmt.Errorf("cannot parse favorites query: %v", err)
+	}
+	var (
+		favID int64
+		page  int64
+	)
+	if id := query.Get("id"); id != "" {
+		if favID, err = str.Cast[int64](id); err != nil {
+			return fmt.Errorf("cannot cast favorite id: %v", err)
+		}
+	}
+	if p := query.Get("p"); p != "" {
+		if page, err = str.Cast[int64](p); err != nil {
+			return fmt.Errorf("cannot cast favorites page: %v", err)
+		}
+	}
+
+	switch link {
+	case favoritesLink:
+		return h.sendFavorites(ctx, m, page)
+
+	case favoriteLink:
+		fav, err := h.storage.FavoriteVacancy(ctx, m.ChatID, favID)
+		if err != nil {
+			return fmt.Errorf("cannot got favorite vacancy from storage: %v", err)
+		}
+		// vacancy already removed from favorites
+		if fav == nil {
+			return h.sendFavorites(ctx, m, page)
+		}
+		return h.sendOrEditMessage(m, newFavoriteMessage(fav, page))
+
+	case favoriteDeleteLink:
+		if err := h.storage.DeleteFavoriteVacancy(ctx, m.ChatID, favID); err != nil {
+			return fmt.Errorf("cannot delete favorite vacancy from storage: %v", err)
+		}
+		h.answerCallback(m.CallbackID, telegram.WithToast("Вакансия удалена из избранного 🗑"))
+
+		return h.sendFavorites(ctx, m, page)
+
+	case favoriteExportLink:
+		fav, err := h.storage.FavoriteVacancy(ctx, m.ChatID, favID)
+		if err != nil {
+			return fmt.Errorf("cannot got favorite vacancy from storage: %v", err)
+		}
+		if fav == nil {
+			return h.sendFavorites(ctx, m, page)
+		}
+		if _, err = h.bot.SendMessage(newFavoriteExportMessage(fav)); err != nil {
+			return fmt.Errorf("cannot send favorite vacancy telegram bot message: %v", err)
+		}
+		h.answerCallback(m.CallbackID, telegram.WithToast("Вакансия отправлена в чат 📤"))
+	}
+	return nil
+}
+
+func (h *Handler) sendFavorites(ctx context.Context, m *telegram.Message, page int64) error {
+	count, err := h.storage.FavoriteVacanciesCount(ctx, m.ChatID)
+	if err != nil {
+		return fmt.Errorf("cannot got favorite vacancies count from storage: %v", err)
+	}
+	pages := (count + favoritesPerPage - 1) / favoritesPerPage
+
+	// page may be out of range after deletion
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	favs, err := h.storage.FavoriteVacancies(ctx, m.ChatID, page*favoritesPerPage, favoritesPerPage)
+	if err != nil {
+		return fmt.Errorf("cannot got favorite vacancies from storage: %v", err)
+	}
+	return h.sendOrEditMessage(m, newFavoritesMessage(m.ChatID, favs, page, pages))
+}
+
+func (h *Handler) sendOrEditMessage(m *telegram.Message, msg *telegram.SendMessage) error {
+	// edit message with pressed button
+	if m.FromCallback() {
+		if _, err := h.bot.EditMessage(msg.ToEditMessage(m.MessageID)); err != nil {
+			return fmt.Errorf("cannot edit telegram bot message: %v", err)
+		}
+		return nil
+	}
+	if _, err := h.bot.SendMessage(msg); err != nil {
+		return fmt.Errorf("cannot send telegram bot message: %v", err)
+	}
+	return nil
+}
@@ -5,53 +5,115 @@ import (
 	"fmt"
 	"main/internal/chats"
 	"main/internal/fetcher"
+	"main/internal/maintenance"
 	"main/internal/model"
 	"main/internal/storage"
 	"main/pkg/cache"
+	"main/pkg/redis"
 	"main/pkg/schedule"
 	"main/pkg/task"
 	"main/pkg/telegram"
-	"main/pkg/timer"
-	"main/pkg/utils"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// chat state is dropped if chat did not press menu buttons during ttl
+	chatsStateTTL     = 1 * time.Hour
+	chatsStateMaxSize = 100_000
+)
+
 type Handler struct {
-	ctx           context.Context
-	bot           telegram.Bot
-	fetcher       fetcher.Fetcher
-	storage       storage.Storage
-	subTasks      task.Queue
-	sendTasks     task.Queue
-	chatsTrees    chats.Trees
-	chatsTimers   cache.MemCache[int64, timer.RefreshTimer]
-	chatsPending  cache.KeyCache[int64]
-	chatsSubVacs  cache.MemCache[int64, *vacancy]
-	chatsSentVacs cache.MemCache[int64, cache.KeyCache[string]]
+	ctx             context.Context
+	config          *Config
+	bot             telegram.Bot
+	fetcher         fetcher.Fetcher
+	storage         storage.Storage
+	subTasks        task.Queue
+	fetchTasks      task.Queue
+	sendTasks       task.Queue
+	chatsDialog     chats.Dialog[*vacancy]
+	caches          *cache.Backend
+	chatsPending    cache.KeyCache[int64]
+	chatsSentBlooms cache.MemCache[int64, cache.BloomFilter]
+	chatsWithSent   cache.KeyCache[int64]
+	locker          redis.Locker
+	events          redis.PubSub
+	schedules       schedule.Scheduler
+	shardSchedules  schedule.Scheduler
+	maintainer      maintenance.Maintainer
+	shards          *shards
+	polls           *pollQueue
+	tasksCancel     context.CancelFunc
+	tasksWG         sync.WaitGroup
 }
 
-func NewHandler(ctx context.Context, bot telegram.Bot, fetcher fetcher.Fetcher, storage storage.Storage) (*Handler, error) {
-	const workers = 100
-
+func NewHandler(ctx context.Context, bot telegram.Bot, fetcher fetcher.Fetcher, storage storage.Storage, caches *cache.Backend, config *Config) (*Handler, error) {
+	const (
+		workers      = 100
+		buffer       = 1000
+		subTimeout   = 30 * time.Second
+		fetchTimeout = 10 * time.Minute
+		sendTimeout  = 1 * time.Minute
+		retryCount   = 3
+		retryWait    = 5 * time.Second
+		// queued tasks are cancelled on shutdown, fetching is repeated by next poll and sending by job lease
+		drainTimeout = 30 * time.Second
+	)
 	h := &Handler{
-		ctx:          ctx,
-		bot:          bot,
-		fetcher:      fetcher,
-		storage:      storage,
-		subTasks:     task.NewQueue(workers),
-		sendTasks:    task.NewQueue(workers),
-		chatsTimers:  cache.NewMemCache[int64, timer.RefreshTimer](),
-		chatsSubVacs: cache.NewMemCache[int64, *vacancy](),
-		chatsPending: cache.NewKeyCache[int64](),
+		ctx:     ctx,
+		config:  config.withDefault(),
+		bot:     bot,
+		fetcher: fetcher,
+		storage: storage,
+		subTasks: task.NewQueue(workers,
+			task.WithBuffer(buffer),
+			task.WithTimeout(subTimeout),
+			task.WithRetries(retryCount, retryWait),
+			task.WithDrainTimeout(drainTimeout),
+		),
+		// subscriptions are pushed again on next schedule, so fetch tasks are not retried
+		fetchTasks: task.NewQueue(workers,
+			task.WithBuffer(buffer),
+			task.WithTimeout(fetchTimeout),
+			task.WithDrainTimeout(drainTimeout),
+		),
+		// delivery jobs are retried by storage leases
+		sendTasks: task.NewQueue(workers,
+			task.WithBuffer(buffer),
+			task.WithTimeout(sendTimeout),
+			task.WithDrainTimeout(drainTimeout),
+		),
+		caches: caches,
+		chatsPending: cache.NewBackendKeyCache[int64](caches, "chats:pending",
+			cache.WithTTL(chatsStateTTL),
+			cache.WithMaxSize(chatsStateMaxSize),
+		),
+		schedules:      schedule.NewScheduler(),
+		shardSchedules: schedule.NewScheduler(),
+		polls:          newPollQueue(),
 	}
+	// chats are marked as having sent vacancies while sent vacancies are kept
+	h.chatsWithSent = cache.NewBackendKeyCache[int64](caches, "chats:with_sent",
+		cache.WithTTL(h.sentVacanciesRetention()),
+	)
 	if err := h.prepareComponents(ctx); err != nil {
 		return nil, fmt.Errorf("handler cannot prepare components: %v", err)
 	}
-	go h.subTasks.ContinuouslyHandle(ctx)
-	go h.sendTasks.ContinuouslyHandle(ctx)
+	tasksCtx, cancel := context.WithCancel(ctx)
+	h.tasksCancel = cancel
+
+	for _, q := range []task.Queue{h.subTasks, h.fetchTasks, h.sendTasks} {
+		q := q
+		h.tasksWG.Add(1)
 
+		go func() {
+			defer h.tasksWG.Done()
+			q.ContinuouslyHandle(tasksCtx)
+		}()
+	}
 	return h, nil
 }
 
@@ -59,52 +121,27 @@ func (h *Handler) prepareComponents(ctx context.Context) error {
 	if err := h.bot.Start(); err != nil {
 		return fmt.Errorf("telegram bot cannot start: %v", err)
 	}
-	if err := h.setChatsSentVacs(ctx); err != nil {
-		return fmt.Errorf("cannot set sent vacancies: %v", err)
+	shards, err := newShards()
+	if err != nil {
+		return fmt.Errorf("cannot create shards: %v", err)
 	}
-	h.setChatsTrees()
+	h.shards = shards
+	h.setEvents()
 
-	return nil
-}
+	h.setChatsSentBlooms()
 
-func (h *Handler) setChatsSentVacs(ctx context.Context) error {
-	vacancies, err := h.storage.SentVacancies(ctx)
-	if err != nil {
-		return fmt.Errorf("cannot got sent vacancies from storage: %v", err)
+	if err := h.setChatsDialog(); err != nil {
+		return fmt.Errorf("cannot set chats dialog: %v", err)
 	}
-	m := cache.NewMemCache[int64, cache.KeyCache[string]]()
-
-	for _, v := range vacancies {
-		m.GetPut(v.ChatID, cache.NewKeyCache[string]()).Put(v.VacancyID)
+	if err := h.setMaintainer(); err != nil {
+		return fmt.Errorf("cannot set maintainer: %v", err)
+	}
+	if err := h.setSchedules(); err != nil {
+		return fmt.Errorf("cannot set schedules: %v", err)
 	}
-	h.chatsSentVacs = m
-
-	return nil
-}
-
-func (h *Handler) HandleSubscriptions(ctx context.Context) error {
-	if err := h.storage.ChatsSubscriptions(ctx, func(sub *model.ChatSubscription) {
-		h.sendTasks.Push(func() error {
-			if err := h.sendSubscriptionVacancies(ctx, sub); err != nil {
-				return fmt.Errorf("cannot send subscription vacancies: %v", err)
-			}
-			log.Infof("subscription %s for chat with id %d handled", sub.Keywords, sub.ChatID)
-			return nil
-		})
-	}); err != nil {
-		return fmt.Errorf("cannot got chats subscription from storage: %v", err)
-	}
-	log.Infof("chat subscriptions handled")
 	return nil
 }
 
-func (h *Handler) HandleSubscriptionsContinuously(ctx context.Context) {
-	schedule.DoWithSchedule("10s", "30s", true, func() error {
-		log.Infof("scheduled handling subscriptions started")
-		return h.HandleSubscriptions(ctx)
-	})
-}
-
 func (h *Handler) fetchVacancies(ctx context.Context, s *model.ChatSubscription) ([]*fetcher.VacancyResponseItem, error) {
 	const (
 		maxDepth = 1000
@@ -138,57 +175,102 @@ func (h *Handler) fetchVacancies(ctx context.Context, s *model.ChatSubscription)
 	return items, nil
 }
 
-func (h *Handler) sendSubscriptionVacancies(ctx context.Context, s *model.ChatSubscription) error {
-	const timeout = 15 * time.Second
-
-	items, err := h.fetchVacancies(ctx, s)
+// enqueueSubscriptionVacancies claims fetched vacancies for subscription and returns count of claimed ones.
+func (h *Handler) enqueueSubscriptionVacancies(ctx context.Context, s *model.ChatSubscription, items []*fetcher.VacancyResponseItem) (int, error) {
+	hiddenEmployers, err := h.chatHiddenEmployers(ctx, s.ChatID)
 	if err != nil {
-		return fmt.Errorf("cannot fetch vacancies: %v", err)
+		return 0, fmt.Errorf("cannot got chat hidden employers: %v", err)
 	}
+	candidates := make([]*fetcher.VacancyResponseItem, 0, len(items))
+	ids := make([]string, 0, len(items))
+
 	for _, item := range items {
 		// if vacancy it is wrong
 		if isWrongVacancy(item) {
 			continue
 		}
-		// if chat id exist in pending chats
-		if h.chatsPending.Exist(s.ChatID) {
-			return nil
-		}
-		// if vacancy id already sent to chat id
-		if h.chatsSentVacs.Exist(s.ChatID) && h.chatsSentVacs.Get(s.ChatID).Exist(item.Id) {
+		// if vacancy employer hidden by chat
+		if hiddenEmployers.Exist(item.Employer.Id) {
 			continue
 		}
-		msg := newVacancyMessage(s.ChatID, s.Keywords, item)
+		candidates = append(candidates, item)
+		ids = append(ids, item.Id)
+	}
+	// vacancies already sent or queued to chat id
+	sent, err := h.chatSentVacancies(ctx, s.ChatID, ids)
+	if err != nil {
+		return 0, fmt.Errorf("cannot got chat sent vacancies: %v", err)
+	}
+	var claimed int
 
-		if _, err = h.bot.SendMessage(msg); err != nil {
-			return fmt.Errorf("cannot send vacancy telegram bot message: %v", err)
+	for _, item := range candidates {
+		if _, ok := sent[item.Id]; ok {
+			continue
 		}
-		// put sent vacancy id for chat id
-		h.chatsSentVacs.GetPut(s.ChatID, cache.NewKeyCache[string]()).Put(item.Id)
-
-		if err = h.storage.PutSentVacancy(ctx, &model.ChatSentVacancy{
-			VacancyID:      item.Id,
+		job, err := newDeliveryJob(s, item)
+		if err != nil {
+			return claimed, fmt.Errorf("cannot create delivery job: %v", err)
+		}
+		// claim vacancy in outbox with its delivery job, already claimed vacancy is skipped
+		ok, err := h.storage.ClaimSentVacancy(ctx, &model.ChatSentVacancy{
 			SubscriptionID: s.SubscriptionID,
-			CreatedAt:      utils.NowTimeUTC(),
-		}); err != nil {
-			return fmt.Errorf("cannot put sent vacancy to storage: %v", err)
+			ChatID:         s.ChatID,
+			VacancyID:      item.Id,
+			VacancyName:    item.Name,
+			Status:         model.SentVacancyPending,
+			CreatedAt:      job.CreatedAt,
+		}, job)
+		if err != nil {
+			return claimed, fmt.Errorf("cannot claim sent vacancy in storage: %v", err)
 		}
-
-		// wait time for chat id
-		<-h.chatsTimers.GetPut(
-			s.ChatID,
-			timer.NewRefreshTimer(timeout, true),
-		).Wait()
+		if ok {
+			claimed++
+		}
+		// put claimed vacancy id for chat id
+		h.putChatSentVacancy(s.ChatID, item.Id)
 	}
-	return nil
+	return claimed, nil
 }
 
 func (h *Handler) HandleMessagesContinuously(ctx context.Context) {
 	h.bot.HandleMessages(func(m *telegram.Message) error {
-		return h.HandleMessages(ctx, m)
+		// chat dialog session is shared between instances serving webhook
+		unlock, err := h.lockChat(ctx, m.ChatID)
+		if err != nil {
+			h.answerCallback(m.CallbackID, telegram.WithAlert("Не удалось выполнить действие, попробуйте позже ❗"))
+			return err
+		}
+		defer unlock()
+
+		if err = h.HandleMessages(ctx, m); err != nil {
+			h.answerCallback(m.CallbackID, telegram.WithAlert("Не удалось выполнить действие, попробуйте позже ❗"))
+			return err
+		}
+		return nil
 	})
 }
 
+func (h *Handler) answerCallback(callbackID string, options ...telegram.CallbackOption) {
+	if err := h.bot.AnswerCallback(callbackID, options...); err != nil {
+		log.Warnf("cannot answer telegram callback query: %v", err)
+	}
+}
+
 func (h *Handler) Shutdown() {
 	h.bot.Shutdown()
+	h.deleteInstance()
+
+	// wait queued tasks
+	h.tasksCancel()
+	h.tasksWG.Wait()
+
+	log.Infof("tasks stats. subscriptions: %+v. fetching: %+v. sending: %+v",
+		h.subTasks.Stats(), h.fetchTasks.Stats(), h.sendTasks.Stats())
+
+	for _, status := range append(h.schedules.Status(), h.shardSchedules.Status()...) {
+		log.Infof("schedule job status: %+v", status)
+	}
+	for _, report := range h.maintainer.Reports() {
+		log.Infof("maintenance policy report: %+v", report)
+	}
 }
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"main/internal/fetcher"
+	"main/internal/maintenance"
+	"time"
+)
+
+const (
+	sentVacanciesPolicy = "sent_vacancies"
+	// sent vacancies are kept longer than vacancies search period, otherwise vacancies are sent again
+	defaultSentVacanciesRetention = 30 * 24 * time.Hour
+	deliveryJobsRetention         = 7 * 24 * time.Hour
+	// chats which blocked bot are kept for a while, so subscriptions are restored if chat is unblocked
+	inactiveSubsRetention = 90 * 24 * time.Hour
+)
+
+func (h *Handler) setMaintainer() error {
+	if retention := h.sentVacanciesRetention(); retention < fetcher.SearchPeriod {
+		return fmt.Errorf("%s policy retention %s is shorter than vacancies search period %s",
+			sentVacanciesPolicy, retention, fetcher.SearchPeriod)
+	}
+	m, err := maintenance.NewMaintainer(h.config.Maintenance,
+		&maintenance.Policy{
+			Name:      sentVacanciesPolicy,
+			Spec:      "@daily",
+			Retention: defaultSentVacanciesRetention,
+			Prune:     h.storage.DeleteSentVacancies,
+		},
+		&maintenance.Policy{
+			Name:      "delivery_jobs",
+			Spec:      "@daily",
+			Retention: deliveryJobsRetention,
+			Prune:     h.storage.DeleteFinishedDeliveryJobs,
+		},
+		&maintenance.Policy{
+			Name:      "inactive_subscriptions",
+			Spec:      "@daily",
+			Retention: inactiveSubsRetention,
+			Prune:     h.storage.DeleteInactiveSubscriptions,
+		},
+		&maintenance.Policy{
+			Name:      "instances",
+			Spec:      "@hourly",
+			Retention: shardsStaleTTL,
+			Prune:     h.storage.DeleteStaleInstances,
+		},
+		&maintenance.Policy{
+			Name:  "sessions",
+			Spec:  "@hourly",
+			Local: true,
+			Prune: h.sweepChatsState,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("cannot create maintainer: %v", err)
+	}
+	h.maintainer = m
+
+	return nil
+}
+
+// sentVacanciesRetention returns configured retention of sent vacancies or default one.
+func (h *Handler) sentVacanciesRetention() time.Duration {
+	if config := h.config.Maintenance; config != nil {
+		if policy := config.Policies[sentVacanciesPolicy]; policy != nil && policy.Retention > 0 {
+			return policy.Retention
+		}
+	}
+	return defaultSentVacanciesRetention
+}
+
+// sweepChatsState removes expired chat sessions and states kept in memory.
+// Caches expire keys by ttl, so retention is not used.
+func (h *Handler) sweepChatsState(_ context.Context, _ time.Time, _ int64) (int64, error) {
+	swept := h.chatsDialog.Sweep() + h.chatsPending.Sweep() + h.chatsWithSent.Sweep()
+
+	if h.chatsSentBlooms != nil {
+		swept += h.chatsSentBlooms.Sweep()
+	}
+	return int64(swept), nil
+}
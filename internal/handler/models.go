@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"encoding/json"
 	"fmt"
+	"main/internal/chats"
 	"main/internal/fetcher"
 	"main/internal/model"
 	"main/pkg/str"
@@ -14,6 +16,37 @@ type vacancy struct {
 	area       string
 	experience string
 	keywords   string
+	urgent     bool
+}
+
+type vacancyJSON struct {
+	Area       string `json:"area"`
+	Experience string `json:"experience"`
+	Keywords   string `json:"keywords"`
+	Urgent     bool   `json:"urgent"`
+}
+
+func (f *vacancy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&vacancyJSON{
+		Area:       f.area,
+		Experience: f.experience,
+		Keywords:   f.keywords,
+		Urgent:     f.urgent,
+	})
+}
+
+func (f *vacancy) UnmarshalJSON(buf []byte) error {
+	v := &vacancyJSON{}
+
+	if err := json.Unmarshal(buf, v); err != nil {
+		return err
+	}
+	f.area = v.Area
+	f.experience = v.Experience
+	f.keywords = v.Keywords
+	f.urgent = v.Urgent
+
+	return nil
 }
 
 func (f *vacancy) IsFilled() bool {
@@ -126,7 +159,7 @@ func newUnsubMessage(chatID int64, subs []*model.ChatSubscription) *telegram.Sen
 	for index, sub := range subs {
 		buttons = append(buttons, telegram.InlineKeyboardButton{
 			Text:    fmt.Sprintf("%d 🌠️ %s", index+1, sub.Keywords),
-			Command: fmt.Sprintf("/unsub?id=%d", sub.SubscriptionID),
+			Command: chats.NewCommand(unsubAction, chats.Int64Param("id", sub.SubscriptionID)),
 		})
 	}
 	buttons = append(buttons, telegram.InlineKeyboardButton{
@@ -146,7 +179,8 @@ func newUnsubMessage(chatID int64, subs []*model.ChatSubscription) *telegram.Sen
 }
 
 func newManMessage(chatID int64) *telegram.SendMessage {
-	text := `Бот имеет следующие команды 📑`
+	text := `Бот имеет следующие команды 📑
+/favorites — избранные вакансии ⭐`
 
 	keyboard := telegram.NewInlineKeyboard(telegram.InColButtonsMarkup,
 		telegram.InlineKeyboardButton{
@@ -179,11 +213,11 @@ func newAreaMessage(chatID int64) *telegram.SendMessage {
 	keyboard := telegram.NewInlineKeyboard(telegram.InColButtonsMarkup,
 		telegram.InlineKeyboardButton{
 			Text:    "Москва",
-			Command: "/area?id=1",
+			Command: chats.NewCommand(areaAction, chats.StringParam("id", "1")),
 		},
 		telegram.InlineKeyboardButton{
 			Text:    "Санкт-Петербург",
-			Command: "/area?id=2",
+			Command: chats.NewCommand(areaAction, chats.StringParam("id", "2")),
 		},
 		telegram.InlineKeyboardButton{
 			Text:    "Назад 🔍",
@@ -203,15 +237,15 @@ func newExperienceMessage(chatID int64) *telegram.SendMessage {
 	keyboard := telegram.NewInlineKeyboard(telegram.InColButtonsMarkup,
 		telegram.InlineKeyboardButton{
 			Text:    "От 1 до 3 лет",
-			Command: "/experience?id=between1And3",
+			Command: chats.NewCommand(experienceAction, chats.StringParam("id", "between1And3")),
 		},
 		telegram.InlineKeyboardButton{
 			Text:    "От 3 до 6 лет",
-			Command: "/experience?id=between3And6",
+			Command: chats.NewCommand(experienceAction, chats.StringParam("id", "between3And6")),
 		},
 		telegram.InlineKeyboardButton{
 			Text:    "Без коммерческого опыта",
-			Command: "/experience?id=noExperience",
+			Command: chats.NewCommand(experienceAction, chats.StringParam("id", "noExperience")),
 		},
 		telegram.InlineKeyboardButton{
 			Text:    "Назад 🔍",
@@ -249,13 +283,18 @@ func newFillFieldsMessage(chatID int64) *telegram.SendMessage {
 }
 
 func newConfirmCancelMessage(chatID int64) *telegram.SendMessage {
-	text := `Подтвердите подписку на вакансию или отмените выбор ✉️`
+	text := `Подтвердите подписку на вакансию или отмените выбор ✉️
+Срочная подписка проверяет новые вакансии чаще ⚡`
 
 	keyboard := telegram.NewInlineKeyboard(telegram.InColButtonsMarkup,
 		telegram.InlineKeyboardButton{
 			Text:    "Подтвердить ✅",
 			Command: "/confirm",
 		},
+		telegram.InlineKeyboardButton{
+			Text:    "Срочная подписка ⚡",
+			Command: chats.NewCommand(confirmAction, chats.StringParam("urgent", "1")),
+		},
 		telegram.InlineKeyboardButton{
 			Text:    "Отмена ❗",
 			Command: "/cancel",
@@ -287,36 +326,67 @@ func newConfirmMessage(chatID int64) *telegram.SendMessage {
 	}
 }
 
-func newVacancyMessage(chatID int64, keywords string, item *fetcher.VacancyResponseItem) *telegram.SendMessage {
+func newVacancyMessage(sub *model.ChatSubscription, item *fetcher.VacancyResponseItem) *telegram.SendMessage {
+	var employerID string
+
+	if employer := item.Employer; employer != nil {
+		employerID = employer.Id
+	}
+	keyboard := newVacancyKeyboard(&vacancyReaction{
+		subID:      sub.SubscriptionID,
+		vacancyID:  item.Id,
+		employerID: employerID,
+	})
+
+	return &telegram.SendMessage{
+		ChatID:   sub.ChatID,
+		Text:     vacancyText(sub.Keywords, item, false),
+		Keyboard: keyboard,
+	}
+}
+
+func newArchivedVacancyMessage(sub *model.ChatSubscription, item *fetcher.VacancyResponseItem) *telegram.SendMessage {
+	return &telegram.SendMessage{
+		ChatID:   sub.ChatID,
+		Text:     vacancyText(sub.Keywords, item, true),
+		Keyboard: newArchivedVacancyKeyboard(),
+	}
+}
+
+func newArchivedVacancyKeyboard() *telegram.InlineKeyboard {
+	return telegram.NewInlineKeyboard(telegram.InColButtonsMarkup,
+		telegram.InlineKeyboardButton{
+			Text:    "Перейти в меню бота 💭",
+			Command: "/start",
+		})
+}
+
+func vacancyText(keywords string, item *fetcher.VacancyResponseItem, archived bool) string {
 	s := strings.Builder{}
 
-	url := fmt.Sprintf("<a href=\"%s\">Новая вакансия</a>", item.AlternateUrl)
-	s.WriteString(fmt.Sprintf("🌠📨🌠📨🌠 %s\n\n", url))
+	if archived {
+		s.WriteString("🌠📨🌠📨🌠 Вакансия закрыта 📦\n\n")
+	} else {
+		url := fmt.Sprintf("<a href=\"%s\">Новая вакансия</a>", item.AlternateUrl)
+		s.WriteString(fmt.Sprintf("🌠📨🌠📨🌠 %s\n\n", url))
+	}
 
 	s.WriteString(fmt.Sprintf("<b>🍪 Подписка</b>\n%s\n\n", str.Sanitize(keywords)))
 
-	s.WriteString(fmt.Sprintf("<b>👔 Название</b>\n%s\n\n", str.Sanitize(item.Name)))
+	if archived {
+		s.WriteString(fmt.Sprintf("<b>👔 Название</b>\n<s>%s</s>\n\n", str.Sanitize(item.Name)))
+	} else {
+		s.WriteString(fmt.Sprintf("<b>👔 Название</b>\n%s\n\n", str.Sanitize(item.Name)))
+	}
 
 	if area := item.Area; area != nil && area.Name != "" {
 		s.WriteString(fmt.Sprintf("<b>🌎 Город</b>\n%s\n\n", str.Sanitize(area.Name)))
 	}
 
-	if salary := item.Salary; salary != nil && salary.Currency != "" {
-		curr := str.Sanitize(salary.Currency)
-		curr = strings.ToUpper(curr)
-
-		if fork := salary.From > 0 && salary.To > 0; fork {
-			s.WriteString(fmt.Sprintf("<b>💶 Зарплата</b>\nОт %d до %d (%s)", salary.From, salary.To, curr))
-		} else if from := salary.From; from > 0 {
-			s.WriteString(fmt.Sprintf("<b>💶 Зарплата</b>\nОт %d (%s)", from, curr))
-		} else if to := salary.To; to > 0 {
-			s.WriteString(fmt.Sprintf("<b>💶 Зарплата</b>\nДо %d (%s)", to, curr))
-		}
-
-		if salary.Gross {
-			s.WriteString(" <i>до вычета НДФЛ</i>")
+	if salary := item.Salary; salary != nil {
+		if salary := salaryText(int64(salary.From), int64(salary.To), salary.Currency, salary.Gross); salary != "" {
+			s.WriteString(fmt.Sprintf("<b>💶 Зарплата</b>\n%s\n\n", salary))
 		}
-		s.WriteString("\n\n")
 	}
 
 	if employer := item.Employer; employer != nil && employer.Name != "" { // TODO: add employer url
@@ -338,11 +408,6 @@ func newVacancyMessage(chatID int64, keywords string, item *fetcher.VacancyRespo
 		}
 	}
 
-	if hhUrl := item.AlternateUrl; hhUrl != "" {
-		hhUrl := fmt.Sprintf("<a href=\"%s\">Ссылка</a>", hhUrl)
-		s.WriteString(fmt.Sprintf("<b>📑 Ссылка на вакансию</b>\n%s\n\n", hhUrl))
-	}
-
 	if pub := item.PublishedAt; pub != "" {
 		const (
 			hhTimeLayout  = "2006-01-02T15:04:05-0700"
@@ -364,7 +429,73 @@ func newVacancyMessage(chatID int64, keywords string, item *fetcher.VacancyRespo
 		}
 		s.WriteString("\n")
 	}
-	text := s.String()
+	return s.String()
+}
+
+func newVacancyKeyboard(r *vacancyReaction) *telegram.InlineKeyboard {
+	buttons := []struct {
+		text     string
+		reaction model.VacancyReaction
+	}{
+		{
+			text:     "Интересно 👍",
+			reaction: model.VacancyLiked,
+		},
+		{
+			text:     "Не интересно 👎",
+			reaction: model.VacancyDisliked,
+		},
+		{
+			text:     "Скрыть компанию 🙈",
+			reaction: model.VacancyEmployerHidden,
+		},
+	}
+	keyboardButtons := make([]telegram.InlineKeyboardButton, 0, len(buttons)+1)
+
+	for _, button := range buttons {
+		text, command := button.text, r.WithReaction(button.reaction).Command()
+
+		// mark button with chosen reaction
+		if button.reaction == r.reaction {
+			text = fmt.Sprint("✅ ", text)
+
+			// hidden employer is shown again by second press
+			if button.reaction == model.VacancyEmployerHidden {
+				command = r.WithReaction(model.VacancyEmployerShown).Command()
+			}
+		}
+		keyboardButtons = append(keyboardButtons, telegram.InlineKeyboardButton{
+			Text:    text,
+			Command: command,
+		})
+	}
+	saveText := "Сохранить ⭐"
+
+	if r.saved {
+		saveText = fmt.Sprint("✅ ", saveText)
+	}
+	keyboardButtons = append(keyboardButtons, telegram.InlineKeyboardButton{
+		Text:    saveText,
+		Command: r.SaveCommand(),
+	})
+
+	return telegram.NewInlineKeyboardLayout().
+		Row(
+			telegram.NewURLButton("Открыть на hh.ru 🔗", fetcher.VacancyAlternateURL(r.vacancyID)),
+			telegram.NewURLButton("Откликнуться 📝", fetcher.VacancyApplyURL(r.vacancyID)),
+		).
+		Grid(2, keyboardButtons...).
+		Row(telegram.InlineKeyboardButton{
+			Text:    "Перейти в меню бота 💭",
+			Command: "/start",
+		}).
+		Keyboard()
+}
+
+func newKeywordsTuningMessage(chatID int64, keywords string, dislikes int64) *telegram.SendMessage {
+	text := fmt.Sprintf(`Вы отметили %d неинтересных вакансий по подписке <b>%s</b> 👎
+Попробуйте уточнить название вакансии: оформите новую подписку и отпишитесь от текущей 🌠`,
+		dislikes, str.Sanitize(keywords))
 
 	keyboard := telegram.NewInlineKeyboard(telegram.InColButtonsMarkup,
 		telegram.InlineKeyboardButton{
@@ -379,6 +510,169 @@ func newVacancyMessage(chatID int64, keywords string, item *fetcher.VacancyRespo
 	}
 }
 
+func salaryText(from, to int64, currency string, gross bool) string {
+	if currency == "" {
+		return ""
+	}
+	curr := str.Sanitize(currency)
+	curr = strings.ToUpper(curr)
+
+	var text string
+
+	if fork := from > 0 && to > 0; fork {
+		text = fmt.Sprintf("От %d до %d (%s)", from, to, curr)
+	} else if from > 0 {
+		text = fmt.Sprintf("От %d (%s)", from, curr)
+	} else if to > 0 {
+		text = fmt.Sprintf("До %d (%s)", to, curr)
+	} else {
+		return ""
+	}
+	if gross {
+		text = fmt.Sprint(text, " <i>до вычета НДФЛ</i>")
+	}
+	return text
+}
+
+func newFavoritesMessage(chatID int64, favs []*model.ChatFavoriteVacancy, page, pages int64) *telegram.SendMessage {
+	if len(favs) == 0 {
+		return &telegram.SendMessage{
+			ChatID: chatID,
+			Text: `В избранном пока нет вакансий ⭐
+Сохраняйте вакансии кнопкой «Сохранить ⭐» под сообщением с вакансией`,
+		}
+	}
+	text := fmt.Sprintf(`Избранные вакансии ⭐
+Страница %d из %d 👀`, page+1, pages)
+
+	buttons := make([]telegram.InlineKeyboardButton, 0, len(favs))
+
+	for index, fav := range favs {
+		name := fav.Name
+
+		if fav.Archived {
+			name = fmt.Sprint(name, " (в архиве)")
+		}
+		buttons = append(buttons, telegram.InlineKeyboardButton{
+			Text:    fmt.Sprintf("%d ⭐ %s", page*favoritesPerPage+int64(index)+1, name),
+			Command: favoriteCommand(favoriteLink, fav.FavoriteID, page),
+		})
+	}
+	var paging []telegram.InlineKeyboardButton
+
+	if page > 0 {
+		paging = append(paging, telegram.InlineKeyboardButton{
+			Text:    "⬅️ Назад",
+			Command: favoritesCommand(page - 1),
+		})
+	}
+	if page < pages-1 {
+		paging = append(paging, telegram.InlineKeyboardButton{
+			Text:    "Вперед ➡️",
+			Command: favoritesCommand(page + 1),
+		})
+	}
+	keyboard := telegram.NewInlineKeyboardLayout().
+		Grid(1, buttons...).
+		Row(paging...).
+		Keyboard()
+
+	return &telegram.SendMessage{
+		ChatID:   chatID,
+		Text:     text,
+		Keyboard: keyboard,
+	}
+}
+
+func newFavoriteMessage(fav *model.ChatFavoriteVacancy, page int64) *telegram.SendMessage {
+	layout := telegram.NewInlineKeyboardLayout()
+
+	if fav.Url != "" && !fav.Archived {
+		layout.Row(telegram.NewURLButton("Открыть на hh.ru 🔗", fav.Url))
+	}
+	keyboard := layout.
+		Row(
+			telegram.InlineKeyboardButton{
+				Text:    "Отправить в чат 📤",
+				Command: favoriteCommand(favoriteExportLink, fav.FavoriteID, page),
+			},
+			telegram.InlineKeyboardButton{
+				Text:    "Удалить 🗑",
+				Command: favoriteCommand(favoriteDeleteLink, fav.FavoriteID, page),
+			},
+		).
+		Row(telegram.InlineKeyboardButton{
+			Text:    "Назад 🔍",
+			Command: favoritesCommand(page),
+		}).
+		Keyboard()
+
+	return &telegram.SendMessage{
+		ChatID:   fav.ChatID,
+		Text:     favoriteText(fav),
+		Keyboard: keyboard,
+	}
+}
+
+func newFavoriteExportMessage(fav *model.ChatFavoriteVacancy) *telegram.SendMessage {
+	return &telegram.SendMessage{
+		ChatID: fav.ChatID,
+		Text:   favoriteText(fav),
+	}
+}
+
+func newFavoriteChangedMessage(fav *model.ChatFavoriteVacancy, changes []string) *telegram.SendMessage {
+	s := strings.Builder{}
+
+	s.WriteString("Изменилась вакансия из избранного 🔔\n\n")
+
+	for _, change := range changes {
+		s.WriteString(fmt.Sprintf("• %s\n", change))
+	}
+	s.WriteString("\n")
+	s.WriteString(favoriteText(fav))
+
+	keyboard := telegram.NewInlineKeyboard(telegram.InColButtonsMarkup,
+		telegram.InlineKeyboardButton{
+			Text:    "Перейти в избранное ⭐",
+			Command: favoritesCommand(0),
+		})
+
+	return &telegram.SendMessage{
+		ChatID:   fav.ChatID,
+		Text:     s.String(),
+		Keyboard: keyboard,
+	}
+}
+
+func favoriteText(fav *model.ChatFavoriteVacancy) string {
+	s := strings.Builder{}
+
+	s.WriteString(fmt.Sprintf("<b>👔 Название</b>\n%s\n\n", str.Sanitize(fav.Name)))
+
+	if fav.Archived {
+		s.WriteString("<b>📦 Вакансия в архиве</b>\n\n")
+	}
+	if area := fav.Area; area != "" {
+		s.WriteString(fmt.Sprintf("<b>🌎 Город</b>\n%s\n\n", str.Sanitize(area)))
+	}
+	if salary := salaryText(fav.SalaryFrom, fav.SalaryTo, fav.SalaryCurrency, fav.SalaryGross); salary != "" {
+		s.WriteString(fmt.Sprintf("<b>💶 Зарплата</b>\n%s\n\n", salary))
+	}
+	if employer := fav.EmployerName; employer != "" {
+		s.WriteString(fmt.Sprintf("<b>⭐ Компания</b>\n%s\n\n", str.Sanitize(employer)))
+	}
+	if hhUrl := fav.Url; hhUrl != "" {
+		hhUrl := fmt.Sprintf("<a href=\"%s\">Ссылка</a>", hhUrl)
+		s.WriteString(fmt.Sprintf("<b>📑 Ссылка на вакансию</b>\n%s\n\n", hhUrl))
+	}
+	const msgTimeLayout = "02-01-2006 15:04"
+
+	s.WriteString(fmt.Sprintf("<b>🕒 Добавлено в избранное</b>\n%s\n", fav.CreatedAt.Format(msgTimeLayout)))
+
+	return s.String()
+}
+
 func isWrongVacancy(item *fetcher.VacancyResponseItem) bool {
 	switch {
 	case
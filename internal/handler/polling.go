@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"main/internal/model"
+	"main/pkg/utils"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// subscriptions due within lookahead are loaded to poll queue once per refresh
+	pollRefresh   = 30 * time.Second
+	pollLookahead = 2 * pollRefresh
+	pollBatchSize = 1000
+
+	// poll intervals are halved when fresh vacancies found and doubled when idle
+	pollMinInterval       = 2 * time.Minute
+	pollMaxInterval       = 1 * time.Hour
+	pollUrgentMinInterval = 30 * time.Second
+	pollUrgentMaxInterval = 5 * time.Minute
+)
+
+// pollGroup is identical subscriptions which are polled by single fetch.
+type pollGroup struct {
+	key        string
+	subs       []*model.ChatSubscription
+	nextPollAt time.Time
+	interval   time.Duration
+	urgent     bool
+	index      int
+}
+
+func pollGroupKey(s *model.ChatSubscription) string {
+	return fmt.Sprintf("%s:%s:%s", s.Area, s.Experience, s.Keywords)
+}
+
+func (g *pollGroup) add(s *model.ChatSubscription) {
+	for _, sub := range g.subs {
+		if sub.SubscriptionID == s.SubscriptionID {
+			return
+		}
+	}
+	g.subs = append(g.subs, s)
+
+	// group is polled as often as its most frequent subscription
+	if g.nextPollAt.IsZero() || s.NextPollAt.Before(g.nextPollAt) {
+		g.nextPollAt = s.NextPollAt
+	}
+	if g.interval == 0 || (s.PollInterval > 0 && s.PollInterval < g.interval) {
+		g.interval = s.PollInterval
+	}
+	g.urgent = g.urgent || s.Urgent
+}
+
+// nextInterval adapts poll interval of group by count of fresh vacancies.
+func (g *pollGroup) nextInterval(fresh int) time.Duration {
+	minInterval, maxInterval := pollMinInterval, pollMaxInterval
+
+	if g.urgent {
+		minInterval, maxInterval = pollUrgentMinInterval, pollUrgentMaxInterval
+	}
+	interval := g.interval
+
+	switch {
+	case interval == 0:
+		interval = minInterval
+	case fresh > 0:
+		interval /= 2
+	default:
+		interval *= 2
+	}
+	if interval < minInterval {
+		interval = minInterval
+	}
+	if interval > maxInterval {
+		interval = maxInterval
+	}
+	return interval
+}
+
+// pollHeap orders groups by next poll time, urgent groups first.
+type pollHeap []*pollGroup
+
+func (h pollHeap) Len() int {
+	return len(h)
+}
+
+func (h pollHeap) Less(i, j int) bool {
+	if h[i].nextPollAt.Equal(h[j].nextPollAt) {
+		return h[i].urgent && !h[j].urgent
+	}
+	return h[i].nextPollAt.Before(h[j].nextPollAt)
+}
+
+func (h pollHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *pollHeap) Push(x any) {
+	g := x.(*pollGroup)
+	g.index = len(*h)
+	*h = append(*h, g)
+}
+
+func (h *pollHeap) Pop() any {
+	old := *h
+	n := len(old)
+	g := old[n-1]
+	old[n-1] = nil
+	g.index = -1
+	*h = old[:n-1]
+	return g
+}
+
+// pollQueue is priority queue of subscriptions groups deduplicated by group key.
+type pollQueue struct {
+	mtx         sync.Mutex
+	groups      pollHeap
+	index       map[string]*pollGroup
+	inFlight    map[string]struct{}
+	refreshedAt time.Time
+}
+
+func newPollQueue() *pollQueue {
+	return &pollQueue{
+		index:    map[string]*pollGroup{},
+		inFlight: map[string]struct{}{},
+	}
+}
+
+// put adds subscription to queued group, subscriptions of polled groups are skipped.
+func (q *pollQueue) put(s *model.ChatSubscription) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	key := pollGroupKey(s)
+
+	if _, ok := q.inFlight[key]; ok {
+		return
+	}
+	if g, ok := q.index[key]; ok {
+		g.add(s)
+		heap.Fix(&q.groups, g.index)
+		return
+	}
+	g := &pollGroup{key: key}
+	g.add(s)
+
+	q.index[key] = g
+	heap.Push(&q.groups, g)
+}
+
+// pop returns group which poll time came and marks it in flight.
+func (q *pollQueue) pop(now time.Time) (*pollGroup, bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if len(q.groups) == 0 || q.groups[0].nextPollAt.After(now) {
+		return nil, false
+	}
+	g := heap.Pop(&q.groups).(*pollGroup)
+
+	delete(q.index, g.key)
+	q.inFlight[g.key] = struct{}{}
+
+	return g, true
+}
+
+// requeue returns group which cannot be polled now back to queue.
+func (q *pollQueue) requeue(g *pollGroup) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	delete(q.inFlight, g.key)
+
+	if _, ok := q.index[g.key]; ok {
+		return
+	}
+	q.index[g.key] = g
+	heap.Push(&q.groups, g)
+}
+
+func (q *pollQueue) done(g *pollGroup) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	delete(q.inFlight, g.key)
+}
+
+// retain keeps queued groups which keys are accepted by keep and returns count of dropped groups.
+func (q *pollQueue) retain(keep func(key string) bool) int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	groups := q.groups[:0]
+
+	for _, g := range q.groups {
+		if keep(g.key) {
+			groups = append(groups, g)
+			continue
+		}
+		delete(q.index, g.key)
+	}
+	dropped := len(q.groups) - len(groups)
+
+	for i := len(groups); i < len(q.groups); i++ {
+		q.groups[i] = nil
+	}
+	q.groups = groups
+
+	for i, g := range q.groups {
+		g.index = i
+	}
+	heap.Init(&q.groups)
+
+	return dropped
+}
+
+// deleteSubscription removes subscription from queued group, group without subscriptions is removed.
+func (q *pollQueue) deleteSubscription(subID int64) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	for _, g := range q.groups {
+		for i, sub := range g.subs {
+			if sub.SubscriptionID != subID {
+				continue
+			}
+			g.subs = append(g.subs[:i], g.subs[i+1:]...)
+
+			if len(g.subs) == 0 {
+				delete(q.index, g.key)
+				heap.Remove(&q.groups, g.index)
+			}
+			return
+		}
+	}
+}
+
+// resetRefresh makes queue loaded from storage on next handling.
+func (q *pollQueue) resetRefresh() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.refreshedAt = time.Time{}
+}
+
+func (q *pollQueue) needRefresh(now time.Time) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if now.Sub(q.refreshedAt) < pollRefresh {
+		return false
+	}
+	q.refreshedAt = now
+	return true
+}
+
+func (h *Handler) HandleSubscriptions(ctx context.Context) error {
+	now := utils.NowTimeUTC()
+
+	// load subscriptions due soon instead of scanning all subscriptions on every tick
+	if h.polls.needRefresh(now) {
+		// only current shard subscriptions are loaded, so batch size is applied per instance
+		subs, err := h.storage.DueSubscriptions(ctx, now.Add(pollLookahead), h.shards.ranges(), pollBatchSize)
+		if err != nil {
+			return fmt.Errorf("cannot got due subscriptions from storage: %v", err)
+		}
+		for _, sub := range subs {
+			// ring may be rebalanced after ranges are taken
+			if h.shards.owns(pollGroupKey(sub)) {
+				h.polls.put(sub)
+			}
+		}
+	}
+	var polled int
+
+	for {
+		g, ok := h.polls.pop(now)
+		if !ok {
+			break
+		}
+		if err := h.fetchTasks.Push(func(ctx context.Context) error {
+			defer h.polls.done(g)
+
+			if err := h.pollSubscriptions(ctx, g); err != nil {
+				return fmt.Errorf("cannot poll subscriptions: %v", err)
+			}
+			return nil
+		}); err != nil {
+			// fetch queue is full, group is polled on next tick
+			h.polls.requeue(g)
+			log.Warnf("cannot push subscriptions %s fetch task: %v", g.key, err)
+			break
+		}
+		polled++
+	}
+	log.Infof("chat subscriptions handled. polled groups: %d", polled)
+	return nil
+}
+
+// pollSubscriptions fetches vacancies once for group and schedules next poll by found fresh vacancies.
+func (h *Handler) pollSubscriptions(ctx context.Context, g *pollGroup) error {
+	interval := g.interval
+
+	if interval == 0 {
+		interval = g.nextInterval(0)
+	}
+	items, err := h.fetchVacancies(ctx, g.subs[0])
+
+	var fresh int
+
+	if err == nil {
+		for _, sub := range g.subs {
+			count, enqueueErr := h.enqueueSubscriptionVacancies(ctx, sub, items)
+			if enqueueErr != nil {
+				err = fmt.Errorf("cannot enqueue subscription vacancies: %v", enqueueErr)
+				break
+			}
+			fresh += count
+			log.Infof("subscription %s for chat with id %d handled", sub.Keywords, sub.ChatID)
+		}
+		if err == nil {
+			interval = g.nextInterval(fresh)
+		}
+	} else {
+		err = fmt.Errorf("cannot fetch vacancies: %v", err)
+	}
+	// current interval is kept on errors
+	nextPollAt := utils.NowTimeUTC().Add(interval)
+
+	for _, sub := range g.subs {
+		if setErr := h.storage.SetSubscriptionPoll(ctx, sub.SubscriptionID, nextPollAt, interval); setErr != nil {
+			log.Errorf("cannot set subscription %d poll in storage: %v", sub.SubscriptionID, setErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/internal/model"
+	"main/internal/storage"
+	"main/pkg/cache"
+	"main/pkg/http"
+	"main/pkg/str"
+	"main/pkg/telegram"
+	"main/pkg/utils"
+	"net/url"
+	"strconv"
+)
+
+const (
+	reactionLink = "react"
+	saveLink     = "save"
+
+	// send keywords tuning suggestion for every dislikesSuggestStep dislikes
+	dislikesSuggestStep = 5
+)
+
+var reactionToasts = map[model.VacancyReaction]string{
+	model.VacancyLiked:          "Отмечено как интересное 👍",
+	model.VacancyDisliked:       "Отмечено как неинтересное 👎",
+	model.VacancyEmployerHidden: "Вакансии компании скрыты 🙈",
+	model.VacancyEmployerShown:  "Вакансии компании снова показываются 👀",
+}
+
+type vacancyReaction struct {
+	subID      int64
+	vacancyID  string
+	employerID string
+	reaction   model.VacancyReaction
+	saved      bool
+}
+
+func (r *vacancyReaction) WithReaction(reaction model.VacancyReaction) *vacancyReaction {
+	c := *r
+	c.reaction = reaction
+	return &c
+}
+
+func (r *vacancyReaction) Command() string {
+	q := r.query()
+	q.Set("t", string(r.reaction))
+
+	return fmt.Sprintf("/%s?%s", reactionLink, q.Encode())
+}
+
+func (r *vacancyReaction) SaveCommand() string {
+	return fmt.Sprintf("/%s?%s", saveLink, r.query().Encode())
+}
+
+func (r *vacancyReaction) query() url.Values {
+	q := url.Values{}
+
+	q.Set("v", r.vacancyID)
+	q.Set("e", r.employerID)
+	q.Set("s", strconv.FormatInt(r.subID, 10))
+
+	return q
+}
+
+func parseVacancyReaction(command string) (*vacancyReaction, error) {
+	q, err := http.ParseQuery(command)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse query: %v", err)
+	}
+	r := &vacancyReaction{
+		vacancyID:  q.Get("v"),
+		employerID: q.Get("e"),
+		reaction:   model.VacancyReaction(q.Get("t")),
+	}
+	if subID := q.Get("s"); subID != "" {
+		if r.subID, err = str.Cast[int64](subID); err != nil {
+			return nil, fmt.Errorf("cannot cast subscription id: %v", err)
+		}
+	}
+	if r.vacancyID == "" {
+		return nil, fmt.Errorf("vacancy id not specified")
+	}
+	return r, nil
+}
+
+func (h *Handler) handleVacancyReaction(ctx context.Context, m *telegram.Message) error {
+	r, err := parseVacancyReaction(m.Command)
+	if err != nil {
+		return fmt.Errorf("cannot parse vacancy reaction: %v", err)
+	}
+	switch r.reaction {
+	case model.VacancyLiked, model.VacancyDisliked, model.VacancyEmployerHidden, model.VacancyEmployerShown:
+	default:
+		return fmt.Errorf("unsupported vacancy reaction: %s", r.reaction)
+	}
+	// keep saved mark on vacancy message keyboard
+	if r.saved, err = h.storage.FavoriteVacancyExist(ctx, m.ChatID, r.vacancyID); err != nil {
+		return fmt.Errorf("cannot got favorite vacancy existence from storage: %v", err)
+	}
+	changed, err := h.putVacancyReaction(ctx, m.ChatID, r)
+	if err != nil {
+		// subscription was deleted after vacancy sent
+		if errors.Is(err, storage.ErrSubscriptionNotFound) {
+			h.answerCallback(m.CallbackID, telegram.WithAlert("Подписка для этой вакансии удалена ❗"))
+			return nil
+		}
+		return fmt.Errorf("cannot put vacancy reaction: %v", err)
+	}
+	// mark chosen reaction on vacancy message keyboard
+	if err = h.bot.EditKeyboard(m.ChatID, m.MessageID, newVacancyKeyboard(r)); err != nil {
+		return fmt.Errorf("cannot edit vacancy message keyboard: %v", err)
+	}
+	h.answerCallback(m.CallbackID, telegram.WithToast(reactionToasts[r.reaction]))
+
+	// repeated dislike does not change dislikes count, so suggestion is not sent again
+	if changed && r.reaction == model.VacancyDisliked && r.subID != 0 {
+		if err = h.suggestKeywordsTuning(ctx, m.ChatID, r.subID); err != nil {
+			return fmt.Errorf("cannot suggest keywords tuning: %v", err)
+		}
+	}
+	return nil
+}
+
+// putVacancyReaction saves reaction and returns true if chat reaction changed.
+// Hidden employers are kept apart from vacancy reactions, so later reactions do not unhide employer,
+// employer is shown again only by its own reaction.
+func (h *Handler) putVacancyReaction(ctx context.Context, chatID int64, r *vacancyReaction) (bool, error) {
+	now := utils.NowTimeUTC()
+
+	switch r.reaction {
+	case model.VacancyEmployerHidden:
+		if r.employerID == "" {
+			return false, fmt.Errorf("employer id not specified")
+		}
+		hidden, err := h.storage.PutHiddenEmployer(ctx, &model.ChatHiddenEmployer{
+			ChatID:     chatID,
+			EmployerID: r.employerID,
+			VacancyID:  r.vacancyID,
+			CreatedAt:  now,
+		})
+		if err != nil {
+			return false, fmt.Errorf("cannot put hidden employer to storage: %v", err)
+		}
+		return hidden, nil
+
+	case model.VacancyEmployerShown:
+		if r.employerID == "" {
+			return false, fmt.Errorf("employer id not specified")
+		}
+		shown, err := h.storage.DeleteHiddenEmployer(ctx, chatID, r.employerID)
+		if err != nil {
+			return false, fmt.Errorf("cannot delete hidden employer from storage: %v", err)
+		}
+		return shown, nil
+	}
+	prev, err := h.storage.VacancyReaction(ctx, chatID, r.vacancyID)
+	if err != nil {
+		return false, fmt.Errorf("cannot got vacancy reaction from storage: %v", err)
+	}
+	if err = h.storage.PutVacancyReaction(ctx, &model.ChatVacancyReaction{
+		SubscriptionID: r.subID,
+		ChatID:         chatID,
+		VacancyID:      r.vacancyID,
+		EmployerID:     r.employerID,
+		Reaction:       r.reaction,
+		CreatedAt:      now,
+	}); err != nil {
+		return false, fmt.Errorf("cannot put vacancy reaction to storage: %w", err)
+	}
+	return prev != r.reaction, nil
+}
+
+func (h *Handler) suggestKeywordsTuning(ctx context.Context, chatID, subID int64) error {
+	dislikes, err := h.storage.SubscriptionReactionsCount(ctx, chatID, subID, model.VacancyDisliked)
+	if err != nil {
+		return fmt.Errorf("cannot got subscription dislikes count from storage: %v", err)
+	}
+	if dislikes == 0 || dislikes%dislikesSuggestStep != 0 {
+		return nil
+	}
+	subs, err := h.storage.ChatSubscriptions(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("cannot got chat subscriptions from storage: %v", err)
+	}
+	for _, sub := range subs {
+		if sub.SubscriptionID != subID {
+			continue
+		}
+		if _, err = h.bot.SendMessage(newKeywordsTuningMessage(chatID, sub.Keywords, dislikes)); err != nil {
+			return fmt.Errorf("cannot send keywords tuning telegram bot message: %v", err)
+		}
+		break
+	}
+	return nil
+}
+
+func (h *Handler) chatHiddenEmployers(ctx context.Context, chatID int64) (cache.KeyCache[string], error) {
+	employers, err := h.storage.HiddenEmployers(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot got hidden employers from storage: %v", err)
+	}
+	c := cache.NewKeyCache[string]()
+
+	for _, employerID := range employers {
+		c.Put(employerID)
+	}
+	return c, nil
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"main/pkg/schedule"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) setSchedules() error {
+	sharedMaintenance, localMaintenance := h.maintainer.Jobs()
+
+	// shard jobs run on every instance
+	shardJobs := []*schedule.Job{
+		{
+			Name:        "shards",
+			Spec:        fmt.Sprint("@every ", shardsHeartbeat),
+			Immediately: true,
+			Func: func(ctx context.Context) error {
+				return h.HandleShards(ctx)
+			},
+		},
+		{
+			Name:        "subscriptions",
+			Spec:        "@every 10s",
+			Immediately: true,
+			ErrBackoff:  30 * time.Second,
+			Func: func(ctx context.Context) error {
+				log.Infof("scheduled handling subscriptions started")
+				return h.HandleSubscriptions(ctx)
+			},
+		},
+	}
+	// local maintenance prunes memory of every instance
+	shardJobs = append(shardJobs, localMaintenance...)
+
+	for _, job := range shardJobs {
+		if err := h.shardSchedules.Add(job); err != nil {
+			return fmt.Errorf("cannot add shard schedule job: %v", err)
+		}
+	}
+	// leader jobs run on single instance
+	jobs := []*schedule.Job{
+		{
+			Name:       "favorites",
+			Spec:       "@hourly",
+			Jitter:     5 * time.Minute,
+			ErrBackoff: 10 * time.Minute,
+			Func: func(ctx context.Context) error {
+				log.Infof("scheduled handling favorite vacancies started")
+				return h.HandleFavorites(ctx)
+			},
+		},
+		{
+			Name:       "sent vacancies",
+			Spec:       "*/30 * * * *",
+			Jitter:     5 * time.Minute,
+			ErrBackoff: 10 * time.Minute,
+			Func: func(ctx context.Context) error {
+				log.Infof("scheduled handling sent vacancies started")
+				return h.HandleSentVacancies(ctx)
+			},
+		},
+	}
+	jobs = append(jobs, sharedMaintenance...)
+
+	for _, job := range jobs {
+		if err := h.schedules.Add(job); err != nil {
+			return fmt.Errorf("cannot add schedule job: %v", err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) HandleSchedulesContinuously(ctx context.Context) {
+	h.schedules.Run(ctx)
+}
+
+// HandleLeading runs work which must be done by single instance: telegram updates polling and leader schedules.
+// Context is cancelled when instance loses leadership, so work is stopped before other instance takes it.
+func (h *Handler) HandleLeading(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		h.bot.Poll(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		h.HandleSchedulesContinuously(ctx)
+	}()
+	wg.Wait()
+}
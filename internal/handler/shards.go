@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"main/pkg/hashring"
+	"main/pkg/utils"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// instance is treated as dead if it missed several heartbeats
+	shardsHeartbeat   = 5 * time.Second
+	shardsInstanceTTL = 4 * shardsHeartbeat
+	// stale instances rows are pruned by maintenance
+	shardsStaleTTL  = 24 * time.Hour
+	shardsReplicas  = 128
+	shardsStopLimit = 5 * time.Second
+)
+
+// shards distributes subscriptions groups between live instances by consistent hashing.
+type shards struct {
+	instanceID string
+	startedAt  time.Time
+	ring       hashring.Ring
+}
+
+func newShards() (*shards, error) {
+	id, err := newInstanceID()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create instance id: %v", err)
+	}
+	s := &shards{
+		instanceID: id,
+		startedAt:  utils.NowTimeUTC(),
+		ring:       hashring.NewRing(shardsReplicas),
+	}
+	// instance owns all groups until other instances are known
+	s.ring.Set(id)
+
+	return s, nil
+}
+
+func newInstanceID() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	buf := make([]byte, 4)
+
+	if _, err = rand.Read(buf); err != nil {
+		return "", fmt.Errorf("cannot read random bytes: %v", err)
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf)), nil
+}
+
+// owns returns true if subscriptions group with key is handled by current instance.
+func (s *shards) owns(key string) bool {
+	node, ok := s.ring.Get(key)
+	return !ok || node == s.instanceID
+}
+
+// ranges returns poll hash ranges of groups handled by current instance.
+func (s *shards) ranges() []hashring.Range {
+	return s.ring.Ranges(s.instanceID)
+}
+
+// HandleShards sends instance heartbeat and rebalances ring by live instances.
+func (h *Handler) HandleShards(ctx context.Context) error {
+	now := utils.NowTimeUTC()
+
+	if err := h.storage.PutInstanceHeartbeat(ctx, h.shards.instanceID, h.shards.startedAt, now); err != nil {
+		return fmt.Errorf("cannot put instance heartbeat to storage: %v", err)
+	}
+	instances, err := h.storage.LiveInstances(ctx, now.Add(-shardsInstanceTTL))
+	if err != nil {
+		return fmt.Errorf("cannot got live instances from storage: %v", err)
+	}
+	// current instance is always in ring, even if its heartbeat is late
+	if !h.shards.ring.Set(append(instances, h.shards.instanceID)...) {
+		return nil
+	}
+	nodes := h.shards.ring.Nodes()
+
+	// drop queued groups moved to other instances and load groups moved to current one
+	dropped := h.polls.retain(h.shards.owns)
+	h.polls.resetRefresh()
+
+	log.Infof("subscriptions shards rebalanced. instances: %d. dropped groups: %d", len(nodes), dropped)
+
+	return nil
+}
+
+// HandleShardsContinuously keeps instance registered and polls owned subscriptions groups.
+// Unlike leader schedules it runs on every instance, so fetching scales with instances count.
+func (h *Handler) HandleShardsContinuously(ctx context.Context) {
+	h.shardSchedules.Run(ctx)
+}
+
+func (h *Handler) deleteInstance() {
+	ctx, cancel := context.WithTimeout(context.Background(), shardsStopLimit)
+	defer cancel()
+
+	// other instances rebalance without waiting instance ttl
+	if err := h.storage.DeleteInstance(ctx, h.shards.instanceID); err != nil {
+		log.Warnf("cannot delete instance from storage: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/internal/fetcher"
+	"main/internal/model"
+	"main/pkg/str"
+	"main/pkg/utils"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// sentVacanciesTrackPeriod matches search period of vacancies request
+	sentVacanciesTrackPeriod = fetcher.SearchPeriod
+	// sent vacancies are checked in batches, so vacancy requests per run are bounded
+	// and every vacancy is checked once per check interval
+	sentVacanciesCheckBatch    = 300
+	sentVacanciesCheckInterval = 6 * time.Hour
+	// favorite vacancies are checked in batches the same way
+	favoriteVacanciesCheckBatch    = 300
+	favoriteVacanciesCheckInterval = 6 * time.Hour
+)
+
+func (h *Handler) HandleFavorites(ctx context.Context) error {
+	var (
+		now           = utils.NowTimeUTC()
+		vacanciesFavs = map[string][]*model.ChatFavoriteVacancy{}
+		checked       []int64
+	)
+	// group favorites by vacancy id for fetch every vacancy once
+	if err := h.storage.ActiveFavoriteVacancies(ctx,
+		now.Add(-favoriteVacanciesCheckInterval),
+		favoriteVacanciesCheckBatch,
+		func(fav *model.ChatFavoriteVacancy) {
+			vacanciesFavs[fav.VacancyID] = append(vacanciesFavs[fav.VacancyID], fav)
+		},
+	); err != nil {
+		return fmt.Errorf("cannot got chats favorite vacancies from storage: %v", err)
+	}
+	for vacancyID, favs := range vacanciesFavs {
+		item, err := h.fetcher.FetchVacancy(ctx, vacancyID)
+
+		if err != nil && !errors.Is(err, fetcher.ErrVacancyNotFound) {
+			// failed vacancy is checked again on next run
+			log.Errorf("cannot fetch favorite vacancy %s: %v", vacancyID, err)
+			continue
+		}
+		for _, fav := range favs {
+			checked = append(checked, fav.FavoriteID)
+
+			if err = h.trackFavoriteVacancy(ctx, fav, item); err != nil {
+				log.Errorf("cannot track favorite vacancy %s for chat with id %d: %v", vacancyID, fav.ChatID, err)
+			}
+		}
+	}
+	if len(checked) > 0 {
+		if err := h.storage.SetFavoriteVacanciesChecked(ctx, checked, now); err != nil {
+			return fmt.Errorf("cannot set favorite vacancies checked in storage: %v", err)
+		}
+	}
+	log.Infof("favorite vacancies handled. checked: %d", len(checked))
+	return nil
+}
+
+func (h *Handler) HandleSentVacancies(ctx context.Context) error {
+	var (
+		now           = utils.NowTimeUTC()
+		vacanciesSent = map[string][]*model.ChatSentVacancy{}
+		subs          = map[int64]*model.ChatSubscription{}
+		checked       []int64
+	)
+	// group sent vacancies by vacancy id for fetch every vacancy once
+	if err := h.storage.ActiveSentVacancies(ctx,
+		now.Add(-sentVacanciesTrackPeriod),
+		now.Add(-sentVacanciesCheckInterval),
+		sentVacanciesCheckBatch,
+		func(sv *model.ChatSentVacancy, sub *model.ChatSubscription) {
+			vacanciesSent[sv.VacancyID] = append(vacanciesSent[sv.VacancyID], sv)
+			subs[sub.SubscriptionID] = sub
+		},
+	); err != nil {
+		return fmt.Errorf("cannot got active sent vacancies from storage: %v", err)
+	}
+	for vacancyID, sentVacs := range vacanciesSent {
+		item, err := h.fetcher.FetchVacancy(ctx, vacancyID)
+
+		// vacancy removed from hh.ru, so message is rebuilt from kept vacancy name
+		if errors.Is(err, fetcher.ErrVacancyNotFound) {
+			item = nil
+		} else if err != nil {
+			// failed vacancy is checked again on next run
+			log.Errorf("cannot fetch sent vacancy %s: %v", vacancyID, err)
+			continue
+		}
+		for _, sv := range sentVacs {
+			checked = append(checked, sv.SentID)
+		}
+		if item != nil && !item.Archived {
+			continue
+		}
+		for _, sv := range sentVacs {
+			if err = h.archiveSentVacancy(ctx, subs[sv.SubscriptionID], sv, item); err != nil {
+				log.Errorf("cannot archive sent vacancy %s for chat with id %d: %v", vacancyID, sv.ChatID, err)
+			}
+		}
+	}
+	if len(checked) > 0 {
+		if err := h.storage.SetSentVacanciesChecked(ctx, checked, now); err != nil {
+			return fmt.Errorf("cannot set sent vacancies checked in storage: %v", err)
+		}
+	}
+	log.Infof("sent vacancies handled. checked: %d", len(checked))
+	return nil
+}
+
+// archiveSentVacancy edits sent vacancy message to closed one.
+// Nil item means the vacancy was removed from hh.ru, so message is built from kept vacancy name.
+func (h *Handler) archiveSentVacancy(ctx context.Context, sub *model.ChatSubscription, sv *model.ChatSentVacancy, item *fetcher.VacancyResponseItem) error {
+	if item == nil {
+		item = &fetcher.VacancyResponseItem{
+			Id:   sv.VacancyID,
+			Name: sv.VacancyName,
+		}
+		// name is not kept for vacancies sent before it was stored
+		if item.Name == "" {
+			item.Name = "Вакансия удалена"
+		}
+	}
+	msg := newArchivedVacancyMessage(sub, item)
+
+	if _, err := h.bot.EditMessage(msg.ToEditMessage(sv.MessageID)); err != nil {
+		handled, handleErr := h.handleChatError(ctx, sv.ChatID, err)
+		if !handled {
+			return fmt.Errorf("cannot edit archived vacancy telegram bot message: %v", err)
+		}
+		if handleErr != nil {
+			return handleErr
+		}
+		// message of unavailable or migrated chat cannot be edited anymore, so it is not retried
+	}
+	if err := h.storage.ArchiveSentVacancy(ctx, sv.SentID); err != nil {
+		return fmt.Errorf("cannot archive sent vacancy in storage: %v", err)
+	}
+	return nil
+}
+
+// trackFavoriteVacancy compares favorite snapshot with actual vacancy and notifies chat about changes.
+// Nil item means the vacancy was removed from hh.ru and treated as archived.
+func (h *Handler) trackFavoriteVacancy(ctx context.Context, prev *model.ChatFavoriteVacancy, item *fetcher.VacancyResponseItem) error {
+	var next *model.ChatFavoriteVacancy
+
+	if item != nil {
+		next = newFavoriteVacancy(prev.ChatID, item)
+	} else {
+		snapshot := *prev
+		next = &snapshot
+		next.Archived = true
+	}
+	next.FavoriteID = prev.FavoriteID
+	next.CreatedAt = prev.CreatedAt
+	next.UpdatedAt = utils.NowTimeUTC()
+
+	changes := favoriteVacancyChanges(prev, next)
+	if len(changes) == 0 {
+		return nil
+	}
+	// snapshot is saved before notification, so failed storage does not notify chat again on every run
+	if err := h.storage.PutFavoriteVacancy(ctx, next); err != nil {
+		return fmt.Errorf("cannot put favorite vacancy to storage: %v", err)
+	}
+	if _, err := h.bot.SendMessage(newFavoriteChangedMessage(next, changes)); err != nil {
+		if handled, err := h.handleChatError(ctx, prev.ChatID, err); handled {
+			return err
+		}
+		return fmt.Errorf("cannot send favorite changed telegram bot message: %v", err)
+	}
+	return nil
+}
+
+func favoriteVacancyChanges(prev, next *model.ChatFavoriteVacancy) []string {
+	var changes []string
+
+	if !prev.Archived && next.Archived {
+		changes = append(changes, "Вакансия перенесена в архив 📦")
+	}
+	if prev.Name != next.Name {
+		changes = append(changes, fmt.Sprintf("Название: %s → %s",
+			changedValue(str.Sanitize(prev.Name)), changedValue(str.Sanitize(next.Name))))
+	}
+	if prev.EmployerName != next.EmployerName {
+		changes = append(changes, fmt.Sprintf("Компания: %s → %s",
+			changedValue(str.Sanitize(prev.EmployerName)), changedValue(str.Sanitize(next.EmployerName))))
+	}
+	prevSalary := salaryText(prev.SalaryFrom, prev.SalaryTo, prev.SalaryCurrency, prev.SalaryGross)
+	nextSalary := salaryText(next.SalaryFrom, next.SalaryTo, next.SalaryCurrency, next.SalaryGross)
+
+	if prevSalary != nextSalary {
+		changes = append(changes, fmt.Sprintf("Зарплата: %s → %s",
+			changedValue(prevSalary), changedValue(nextSalary)))
+	}
+	return changes
+}
+
+func changedValue(value string) string {
+	if value == "" {
+		return "не указано"
+	}
+	return value
+}
@@ -0,0 +1,53 @@
+package maintenance
+
+import "time"
+
+const (
+	defaultBatchSize  = 1000
+	defaultMaxBatches = 1000
+	defaultBatchPause = 100 * time.Millisecond
+)
+
+type Config struct {
+	// BatchSize limits rows deleted by one statement, so rows are not locked for long
+	BatchSize int64 `yaml:"batch_size"`
+	// MaxBatches limits batches of one policy run, remaining rows are pruned by next run
+	MaxBatches int `yaml:"max_batches"`
+	// BatchPause lets other transactions take locks between batches
+	BatchPause time.Duration            `yaml:"batch_pause"`
+	Policies   map[string]*PolicyConfig `yaml:"policies"`
+}
+
+// PolicyConfig overrides policy defaults.
+type PolicyConfig struct {
+	Spec      string        `yaml:"spec"`
+	Retention time.Duration `yaml:"retention"`
+	Disabled  bool          `yaml:"disabled"`
+}
+
+func (c *Config) withDefault() *Config {
+	config := &Config{
+		BatchSize:  defaultBatchSize,
+		MaxBatches: defaultMaxBatches,
+		BatchPause: defaultBatchPause,
+		Policies:   map[string]*PolicyConfig{},
+	}
+	if c == nil {
+		return config
+	}
+	if c.BatchSize > 0 {
+		config.BatchSize = c.BatchSize
+	}
+	if c.MaxBatches > 0 {
+		config.MaxBatches = c.MaxBatches
+	}
+	if c.BatchPause > 0 {
+		config.BatchPause = c.BatchPause
+	}
+	for name, policy := range c.Policies {
+		if policy != nil {
+			config.Policies[name] = policy
+		}
+	}
+	return config
+}
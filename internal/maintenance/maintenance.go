@@ -0,0 +1,202 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"main/pkg/schedule"
+	"main/pkg/utils"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Policy prunes one kind of historical data.
+type Policy struct {
+	Name string
+	// Spec and Retention are used if policy is not configured
+	Spec      string
+	Retention time.Duration
+	// Local policy prunes process memory data, so it runs on every instance
+	Local bool
+	// Prune deletes batch of records older than before and returns count of deleted records.
+	// Policy without batches returns count less than limit
+	Prune func(ctx context.Context, before time.Time, limit int64) (int64, error)
+}
+
+// Report is result of last policy run.
+type Report struct {
+	Policy     string
+	Pruned     int64
+	Batches    int
+	Completed  bool
+	Err        string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+type Maintainer interface {
+	// Jobs returns schedule jobs of enabled policies, local policies jobs are returned separately
+	Jobs() (shared []*schedule.Job, local []*schedule.Job)
+	Run(ctx context.Context, name string) (*Report, error)
+	Reports() []Report
+}
+
+type maintainer struct {
+	mtx      sync.Mutex
+	config   *Config
+	policies map[string]*Policy
+	reports  map[string]Report
+}
+
+func NewMaintainer(config *Config, policies ...*Policy) (Maintainer, error) {
+	m := &maintainer{
+		config:   config.withDefault(),
+		policies: make(map[string]*Policy, len(policies)),
+		reports:  map[string]Report{},
+	}
+	for _, policy := range policies {
+		if policy.Prune == nil {
+			return nil, fmt.Errorf("policy %s prune func not specified", policy.Name)
+		}
+		if _, ok := m.policies[policy.Name]; ok {
+			return nil, fmt.Errorf("policy %s declared twice", policy.Name)
+		}
+		m.policies[policy.Name] = policy
+	}
+	// configured policies must be known, e.g. to catch typos in config
+	for name := range m.config.Policies {
+		if _, ok := m.policies[name]; !ok {
+			return nil, fmt.Errorf("unknown policy %s in config", name)
+		}
+	}
+	return m, nil
+}
+
+func (m *maintainer) Jobs() ([]*schedule.Job, []*schedule.Job) {
+	var shared, local []*schedule.Job
+
+	for _, name := range m.names() {
+		name := name
+		policy := m.policies[name]
+		spec, _, disabled := m.policyConfig(policy)
+
+		if disabled {
+			log.Infof("maintenance policy %s disabled", name)
+			continue
+		}
+		job := &schedule.Job{
+			Name:       fmt.Sprint("maintenance ", name),
+			Spec:       spec,
+			Jitter:     time.Minute,
+			ErrBackoff: 10 * time.Minute,
+			Func: func(ctx context.Context) error {
+				_, err := m.Run(ctx, name)
+				return err
+			},
+		}
+		if policy.Local {
+			local = append(local, job)
+		} else {
+			shared = append(shared, job)
+		}
+	}
+	return shared, local
+}
+
+// Run prunes policy records in batches until batch is not full or max batches reached.
+func (m *maintainer) Run(ctx context.Context, name string) (*Report, error) {
+	policy, ok := m.policies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy %s", name)
+	}
+	_, retention, _ := m.policyConfig(policy)
+
+	report := &Report{
+		Policy:    name,
+		StartedAt: utils.NowTimeUTC(),
+	}
+	before := report.StartedAt.Add(-retention)
+
+	err := m.prune(ctx, policy, before, report)
+	if err != nil {
+		report.Err = err.Error()
+	}
+	report.FinishedAt = utils.NowTimeUTC()
+
+	m.mtx.Lock()
+	m.reports[name] = *report
+	m.mtx.Unlock()
+
+	log.Infof("maintenance policy %s pruned: %d. batches: %d. completed: %t. took: %s",
+		name, report.Pruned, report.Batches, report.Completed, report.FinishedAt.Sub(report.StartedAt))
+
+	if err != nil {
+		return report, fmt.Errorf("cannot prune policy %s: %v", name, err)
+	}
+	return report, nil
+}
+
+func (m *maintainer) prune(ctx context.Context, policy *Policy, before time.Time, report *Report) error {
+	for report.Batches < m.config.MaxBatches {
+		pruned, err := policy.Prune(ctx, before, m.config.BatchSize)
+		if err != nil {
+			return err
+		}
+		report.Pruned += pruned
+		report.Batches++
+
+		if pruned < m.config.BatchSize {
+			report.Completed = true
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.config.BatchPause):
+		}
+	}
+	return nil
+}
+
+func (m *maintainer) Reports() []Report {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	reports := make([]Report, 0, len(m.reports))
+
+	for _, name := range m.names() {
+		if report, ok := m.reports[name]; ok {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+func (m *maintainer) policyConfig(policy *Policy) (string, time.Duration, bool) {
+	spec, retention := policy.Spec, policy.Retention
+
+	config, ok := m.config.Policies[policy.Name]
+	if !ok {
+		return spec, retention, false
+	}
+	if config.Spec != "" {
+		spec = config.Spec
+	}
+	if config.Retention > 0 {
+		retention = config.Retention
+	}
+	return spec, retention, config.Disabled
+}
+
+func (m *maintainer) names() []string {
+	names := make([]string, 0, len(m.policies))
+
+	for name := range m.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -9,7 +9,12 @@ type ChatSubscription struct {
 	Area           string
 	Keywords       string
 	Experience     string
-	CreatedAt      time.Time
+	Urgent         bool
+	NextPollAt     time.Time
+	PollInterval   time.Duration
+	// PollHash is hash of subscriptions group key, so instances select owned groups by hash ranges
+	PollHash  uint64
+	CreatedAt time.Time
 }
 
 type ChatSubscriptionSet struct {
@@ -21,12 +26,100 @@ type ChatSubscriptionSet struct {
 	Experience      string
 }
 
+type SentVacancyStatus string
+
+const (
+	// SentVacancyPending vacancy claimed for chat and waits for delivery
+	SentVacancyPending SentVacancyStatus = "pending"
+	SentVacancySent    SentVacancyStatus = "sent"
+)
+
 type ChatSentVacancy struct {
 	SentID         int64
 	SubscriptionID int64
 	ChatID         int64
 	VacancyID      string
+	// VacancyName is kept to mark message closed if vacancy is removed from hh.ru
+	VacancyName string
+	MessageID   int64
+	Status      SentVacancyStatus
+	Archived    bool
+	CreatedAt   time.Time
+	SentAt      time.Time
+	CheckedAt   time.Time
+}
+
+type VacancyReaction string
+
+const (
+	VacancyLiked          VacancyReaction = "like"
+	VacancyDisliked       VacancyReaction = "dislike"
+	VacancyEmployerHidden VacancyReaction = "hide"
+	// VacancyEmployerShown undoes hidden employer
+	VacancyEmployerShown VacancyReaction = "show"
+)
+
+type ChatVacancyReaction struct {
+	ReactionID     int64
+	SubscriptionID int64
+	ChatID         int64
+	VacancyID      string
+	EmployerID     string
+	Reaction       VacancyReaction
+	CreatedAt      time.Time
+}
+
+type ChatHiddenEmployer struct {
+	HiddenID   int64
+	ChatID     int64
+	EmployerID string
+	// VacancyID is vacancy which employer was hidden from
+	VacancyID string
+	CreatedAt time.Time
+}
+
+type ChatFavoriteVacancy struct {
+	FavoriteID     int64
+	ChatID         int64
+	VacancyID      string
+	Name           string
+	EmployerID     string
+	EmployerName   string
+	Area           string
+	SalaryFrom     int64
+	SalaryTo       int64
+	SalaryCurrency string
+	SalaryGross    bool
+	Url            string
+	Archived       bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type DeliveryJobStatus string
+
+const (
+	DeliveryJobPending DeliveryJobStatus = "pending"
+	DeliveryJobLeased  DeliveryJobStatus = "leased"
+	DeliveryJobDone    DeliveryJobStatus = "done"
+	DeliveryJobDead    DeliveryJobStatus = "dead"
+)
+
+type DeliveryJob struct {
+	JobID          int64
+	SentID         int64
+	SubscriptionID int64
+	ChatID         int64
+	VacancyID      string
+	Payload        []byte
+	Status         DeliveryJobStatus
+	Attempts       int64
+	MaxAttempts    int64
+	LastError      string
+	AvailableAt    time.Time
+	LeasedUntil    time.Time
 	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
 type ChatTree struct {
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"main/internal/model"
+	"main/pkg/hashring"
 	"main/pkg/postgres"
 	"main/pkg/retries"
+	"main/pkg/utils"
 	"regexp"
 	"strings"
 	"time"
@@ -40,7 +42,7 @@ func (s *storage) ChatSubscriptions(ctx context.Context, chatID int64) ([]*model
             keywords,
             experience,
             created_at
-        FROM chat_subscriptions WHERE chat_id = $1`)
+        FROM chat_subscriptions WHERE chat_id = $1 AND active = true`)
 
 	var (
 		rows pgx.Rows
@@ -90,8 +92,18 @@ func (s *storage) PutChatSubscription(ctx context.Context, sub *model.ChatSubscr
             area,
             keywords,
             experience,
+            urgent,
+            next_poll_at,
+            poll_interval,
+            poll_hash,
             created_at
-        ) VALUES ($1, $2, $3, $4, $5, $6)`,
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (chat_id, area, keywords, experience) DO UPDATE SET
+            active = true,
+            poll_hash = EXCLUDED.poll_hash,
+            urgent = EXCLUDED.urgent,
+            next_poll_at = EXCLUDED.next_poll_at,
+            poll_interval = EXCLUDED.poll_interval`,
 	)
 	return retries.DoWithRetries(retryCount, retryWait, func() error {
 		if _, err := s.client.Exec(ctx, query,
@@ -101,6 +113,10 @@ func (s *storage) PutChatSubscription(ctx context.Context, sub *model.ChatSubscr
 				sub.Area,
 				sub.Keywords,
 				sub.Experience,
+				sub.Urgent,
+				sub.NextPollAt,
+				int64(sub.PollInterval/time.Second),
+				pollHash(sub.PollHash),
 				sub.CreatedAt,
 			)...,
 		); err != nil {
@@ -110,6 +126,114 @@ func (s *storage) PutChatSubscription(ctx context.Context, sub *model.ChatSubscr
 	})
 }
 
+// DueSubscriptions returns active subscriptions of passed poll hash ranges which should be polled until passed time,
+// earliest first. Ranges are filtered in query, so limit is applied to subscriptions of caller shard only.
+func (s *storage) DueSubscriptions(ctx context.Context, until time.Time, ranges []hashring.Range, limit int64) ([]*model.ChatSubscription, error) {
+	query := sanitizeQuery(
+		`SELECT
+            subscription_id,
+            chat_id,
+            user_id,
+            area,
+            keywords,
+            experience,
+            urgent,
+            next_poll_at,
+            poll_interval,
+            poll_hash,
+            created_at
+        FROM chat_subscriptions WHERE active = true AND next_poll_at <= $1 AND EXISTS (
+            SELECT 1 FROM UNNEST($3::BIGINT[], $4::BIGINT[]) AS r(hash_from, hash_to)
+            WHERE poll_hash BETWEEN r.hash_from AND r.hash_to
+        )
+        ORDER BY next_poll_at
+        LIMIT $2`)
+
+	from := make([]int64, 0, len(ranges))
+	to := make([]int64, 0, len(ranges))
+
+	for _, r := range ranges {
+		from = append(from, pollHash(r.From))
+		to = append(to, pollHash(r.To))
+	}
+
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err = s.client.Query(ctx, query,
+			postgres.SingleQuote(until),
+			postgres.SingleQuote(limit),
+			// ranges arrays passed as is, quote supports scalar values only
+			from,
+			to,
+		)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+	var (
+		subs []*model.ChatSubscription
+		ok   bool
+	)
+	for {
+		var (
+			sub      = &model.ChatSubscription{}
+			interval int64
+			hash     int64
+		)
+		if ok, err = scanQueriedRow(rows,
+			&sub.SubscriptionID,
+			&sub.ChatID,
+			&sub.UserID,
+			&sub.Area,
+			&sub.Keywords,
+			&sub.Experience,
+			&sub.Urgent,
+			&sub.NextPollAt,
+			&interval,
+			&hash,
+			&sub.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("cannot scan queried row: %s: %v", query, err)
+		}
+		if !ok {
+			break
+		}
+		sub.PollInterval = time.Duration(interval) * time.Second
+		sub.PollHash = uint64(hash) ^ 1<<63
+
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+func (s *storage) SetSubscriptionPoll(ctx context.Context, subID int64, nextPollAt time.Time, interval time.Duration) error {
+	query := sanitizeQuery(
+		`UPDATE chat_subscriptions SET
+            next_poll_at = $2,
+            poll_interval = $3
+        WHERE subscription_id = $1`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				subID,
+				nextPollAt,
+				int64(interval/time.Second),
+			)...,
+		); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
 func (s *storage) ChatsSubscriptions(ctx context.Context, callback func(sub *model.ChatSubscription)) error {
 	query := sanitizeQuery(
 		`SELECT
@@ -120,7 +244,7 @@ func (s *storage) ChatsSubscriptions(ctx context.Context, callback func(sub *mod
             keywords,
             experience,
             created_at
-        FROM chat_subscriptions`)
+        FROM chat_subscriptions WHERE active = true`)
 
 	var (
 		rows pgx.Rows
@@ -179,7 +303,7 @@ func (s *storage) ChatSubscriptionsSets(ctx context.Context, callback func(subSe
                 ARRAY_AGG(subscription_id) OVER (PARTITION BY area, LOWER(keywords)) as subscriptions_ids,
                 ARRAY_AGG(chat_id) OVER (PARTITION BY area, LOWER(keywords)) as chat_ids,
                 ARRAY_AGG(user_id) OVER (PARTITION BY area, LOWER(keywords)) as user_ids
-            FROM chat_subscriptions
+            FROM chat_subscriptions WHERE active = true
         ) as QUERY;`)
 
 	var (
@@ -218,24 +342,39 @@ func (s *storage) ChatSubscriptionsSets(ctx context.Context, callback func(subSe
 	return nil
 }
 
-func (s *storage) SentVacancies(ctx context.Context) ([]*model.ChatSentVacancy, error) {
+// SentVacancyIDs returns passed vacancy ids which were already sent or claimed for chat.
+func (s *storage) SentVacancyIDs(ctx context.Context, chatID int64, vacancyIDs []string) ([]string, error) {
 	query := sanitizeQuery(
 		`SELECT
-            sent_id, 
-            sv.subscription_id,
-            chat_id,
-            vacancy_id, 
-            sv.created_at
-    FROM chat_sent_vacancies AS sv 
-        INNER JOIN chat_subscriptions AS s 
-    ON sv.subscription_id = s.subscription_id`)
+            vacancy_id
+        FROM chat_sent_vacancies WHERE chat_id = $1 AND vacancy_id = ANY($2)`)
+
+	return s.queryVacancyIDs(ctx, query,
+		postgres.SingleQuote(chatID),
+		// ids array passed as is, quote supports scalar values only
+		vacancyIDs,
+	)
+}
+
+// ChatSentVacancyIDs returns ids of last sent or claimed vacancies for chat.
+func (s *storage) ChatSentVacancyIDs(ctx context.Context, chatID int64, limit int64) ([]string, error) {
+	query := sanitizeQuery(
+		`SELECT
+            vacancy_id
+        FROM chat_sent_vacancies WHERE chat_id = $1
+        ORDER BY created_at DESC
+        LIMIT $2`)
+
+	return s.queryVacancyIDs(ctx, query, postgres.MultiQuote(chatID, limit)...)
+}
 
+func (s *storage) queryVacancyIDs(ctx context.Context, query string, args ...any) ([]string, error) {
 	var (
 		rows pgx.Rows
 		err  error
 	)
 	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
-		rows, err = s.client.Query(ctx, query)
+		rows, err = s.client.Query(ctx, query, args...)
 		if err != nil {
 			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
 		}
@@ -245,44 +384,276 @@ func (s *storage) SentVacancies(ctx context.Context) ([]*model.ChatSentVacancy,
 		return nil, err
 	}
 	var (
-		sv []*model.ChatSentVacancy
-		ok bool
+		ids []string
+		ok  bool
 	)
 	for {
-		s := &model.ChatSentVacancy{}
+		var id string
 
-		if ok, err = scanQueriedRow(rows,
-			&s.SentID,
-			&s.SubscriptionID,
-			&s.ChatID,
-			&s.VacancyID,
-			&s.CreatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("cannot scan queried row: %v", err)
+		if ok, err = scanQueriedRow(rows, &id); err != nil {
+			return nil, fmt.Errorf("cannot scan queried row: %s: %v", query, err)
 		}
 		if !ok {
 			break
 		}
-		sv = append(sv, s)
+		ids = append(ids, id)
 	}
-	return sv, nil
+	return ids, nil
 }
 
-func (s *storage) PutSentVacancy(ctx context.Context, sv *model.ChatSentVacancy) error {
+// DeleteSentVacancies deletes batch of sent vacancies created before passed time and returns count of deleted rows.
+func (s *storage) DeleteSentVacancies(ctx context.Context, before time.Time, limit int64) (int64, error) {
 	query := sanitizeQuery(
+		`DELETE FROM chat_sent_vacancies WHERE sent_id IN (
+            SELECT sent_id FROM chat_sent_vacancies WHERE created_at < $1
+            LIMIT $2
+        )`)
+
+	var deleted int64
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		tag, err := s.client.Exec(ctx, query, postgres.MultiQuote(before, limit)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		deleted = tag.RowsAffected()
+		return nil
+
+	}); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// ClaimSentVacancy records vacancy as pending for subscription and enqueues its delivery job in one transaction.
+// Reports false if vacancy already claimed for subscription or chat.
+func (s *storage) ClaimSentVacancy(ctx context.Context, sv *model.ChatSentVacancy, job *model.DeliveryJob) (bool, error) {
+	claimQuery := sanitizeQuery(
 		`INSERT INTO chat_sent_vacancies(
             subscription_id,
+            chat_id,
             vacancy_id,
+            vacancy_name,
+            status,
+            archived,
             created_at
-        ) VALUES ($1, $2, $3)`)
+        ) VALUES ($1, $2, $3, $4, $5, false, $6)
+        ON CONFLICT DO NOTHING
+        RETURNING sent_id`)
+
+	jobQuery := sanitizeQuery(
+		`INSERT INTO delivery_jobs(
+            sent_id,
+            subscription_id,
+            chat_id,
+            vacancy_id,
+            payload,
+            status,
+            attempts,
+            max_attempts,
+            available_at,
+            created_at,
+            updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $8, $9, $9)
+        ON CONFLICT (subscription_id, vacancy_id) DO NOTHING`)
+
+	var claimed bool
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		return s.client.BeginTxFunc(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			rows, err := tx.Query(ctx, claimQuery,
+				postgres.MultiQuote(
+					sv.SubscriptionID,
+					sv.ChatID,
+					sv.VacancyID,
+					sv.VacancyName,
+					string(model.SentVacancyPending),
+					sv.CreatedAt,
+				)...,
+			)
+			if err != nil {
+				return fmt.Errorf("cannot do postgres tx query: %s: %v", claimQuery, err)
+			}
+			claimed, err = scanQueriedRow(rows, &sv.SentID)
+			rows.Close()
+
+			if err != nil {
+				return fmt.Errorf("cannot scan queried row: %s: %v", claimQuery, err)
+			}
+			if !claimed {
+				return nil
+			}
+			job.SentID = sv.SentID
+
+			if _, err = tx.Exec(ctx, jobQuery,
+				postgres.SingleQuote(job.SentID),
+				postgres.SingleQuote(job.SubscriptionID),
+				postgres.SingleQuote(job.ChatID),
+				postgres.SingleQuote(job.VacancyID),
+				// payload passed as is to keep json intact
+				string(job.Payload),
+				postgres.SingleQuote(string(job.Status)),
+				postgres.SingleQuote(job.MaxAttempts),
+				postgres.SingleQuote(job.AvailableAt),
+				postgres.SingleQuote(job.CreatedAt),
+			); err != nil {
+				return fmt.Errorf("cannot do postgres tx exec: %s: %v", jobQuery, err)
+			}
+			return nil
+		})
+	}); err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
+
+// MarkVacancySent stores message id of delivered vacancy and completes its delivery job in one transaction.
+func (s *storage) MarkVacancySent(ctx context.Context, job *model.DeliveryJob, messageID int64) error {
+	sentQuery := sanitizeQuery(
+		`UPDATE chat_sent_vacancies SET
+            status = $2,
+            message_id = $3,
+            sent_at = $4
+        WHERE sent_id = $1 AND status = $5`)
+
+	jobQuery := sanitizeQuery(
+		`UPDATE delivery_jobs SET
+            status = $2,
+            leased_until = NULL,
+            updated_at = $3
+        WHERE job_id = $1`)
+
+	now := utils.NowTimeUTC()
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		return s.client.BeginTxFunc(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, sentQuery,
+				postgres.MultiQuote(
+					job.SentID,
+					string(model.SentVacancySent),
+					messageID,
+					now,
+					string(model.SentVacancyPending),
+				)...,
+			); err != nil {
+				return fmt.Errorf("cannot do postgres tx exec: %s: %v", sentQuery, err)
+			}
+			if _, err := tx.Exec(ctx, jobQuery,
+				postgres.MultiQuote(
+					job.JobID,
+					string(model.DeliveryJobDone),
+					now,
+				)...,
+			); err != nil {
+				return fmt.Errorf("cannot do postgres tx exec: %s: %v", jobQuery, err)
+			}
+			return nil
+		})
+	})
+}
+
+// SentVacancyStatus returns status of sent vacancy and message id if vacancy delivered.
+func (s *storage) SentVacancyStatus(ctx context.Context, sentID int64) (model.SentVacancyStatus, int64, error) {
+	query := sanitizeQuery(
+		`SELECT
+            status,
+            COALESCE(message_id, 0)
+        FROM chat_sent_vacancies WHERE sent_id = $1`)
+
+	var (
+		status    string
+		messageID int64
+	)
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err := s.client.QueryRow(ctx, query, postgres.SingleQuote(sentID)).Scan(&status, &messageID); err != nil {
+			return fmt.Errorf("cannot do postgres query row: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return "", 0, err
+	}
+	return model.SentVacancyStatus(status), messageID, nil
+}
+
+// ActiveSentVacancies returns batch of not archived sent vacancies of active subscriptions created since passed time
+// which were not checked after checkedBefore, least recently checked first. Subscription is passed with its keywords.
+func (s *storage) ActiveSentVacancies(ctx context.Context, since, checkedBefore time.Time, limit int64, callback func(sv *model.ChatSentVacancy, sub *model.ChatSubscription)) error {
+	query := sanitizeQuery(
+		`SELECT
+            sv.sent_id,
+            sv.subscription_id,
+            sv.chat_id,
+            sv.vacancy_id,
+            COALESCE(sv.vacancy_name, ''),
+            sv.message_id,
+            sv.archived,
+            sv.created_at,
+            s.keywords
+    FROM chat_sent_vacancies AS sv
+        INNER JOIN chat_subscriptions AS s
+    ON sv.subscription_id = s.subscription_id
+    WHERE sv.status = $2 AND sv.archived = false AND sv.message_id IS NOT NULL AND sv.created_at >= $1
+        AND (sv.checked_at IS NULL OR sv.checked_at < $3) AND s.active = true
+    ORDER BY sv.checked_at NULLS FIRST
+    LIMIT $4`)
+
+	var (
+		rows pgx.Rows
+		err  error
+		ok   bool
+	)
+	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err = s.client.Query(ctx, query, postgres.MultiQuote(since, string(model.SentVacancySent), checkedBefore, limit)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return err
+	}
+	for {
+		var (
+			sv  = &model.ChatSentVacancy{}
+			sub = &model.ChatSubscription{}
+		)
+		if ok, err = scanQueriedRow(rows,
+			&sv.SentID,
+			&sv.SubscriptionID,
+			&sv.ChatID,
+			&sv.VacancyID,
+			&sv.VacancyName,
+			&sv.MessageID,
+			&sv.Archived,
+			&sv.CreatedAt,
+			&sub.Keywords,
+		); err != nil {
+			return fmt.Errorf("cannot scan queried row: %v", err)
+		}
+		if !ok {
+			break
+		}
+		sub.SubscriptionID = sv.SubscriptionID
+		sub.ChatID = sv.ChatID
+
+		callback(sv, sub)
+	}
+	return nil
+}
+
+// SetSentVacanciesChecked sets check time of sent vacancies, so they are checked again after check interval.
+func (s *storage) SetSentVacanciesChecked(ctx context.Context, sentIDs []int64, checkedAt time.Time) error {
+	query := sanitizeQuery(
+		`UPDATE chat_sent_vacancies
+            SET checked_at = $2
+        WHERE sent_id = ANY($1)`)
 
 	return retries.DoWithRetries(retryCount, retryWait, func() error {
 		if _, err := s.client.Exec(ctx, query,
-			postgres.MultiQuote(
-				sv.SubscriptionID,
-				sv.VacancyID,
-				sv.CreatedAt,
-			)...,
+			// ids array passed as is, quote supports scalar values only
+			sentIDs,
+			postgres.SingleQuote(checkedAt),
 		); err != nil {
 			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
 		}
@@ -290,20 +661,906 @@ func (s *storage) PutSentVacancy(ctx context.Context, sv *model.ChatSentVacancy)
 	})
 }
 
-func (s *storage) DeleteChatSubscription(ctx context.Context, subID int64) error {
+func (s *storage) ArchiveSentVacancy(ctx context.Context, sentID int64) error {
+	query := sanitizeQuery(
+		`UPDATE chat_sent_vacancies
+            SET archived = true
+        WHERE sent_id = $1`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query, postgres.SingleQuote(sentID)); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+func (s *storage) DeleteChatSubscription(ctx context.Context, chatID, subID int64) error {
 	query := sanitizeQuery(
 		`DELETE 
             FROM chat_subscriptions 
-        WHERE subscription_id = $1`)
+        WHERE chat_id = $1 AND subscription_id = $2`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		tag, err := s.client.Exec(ctx, query, postgres.MultiQuote(chatID, subID)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrSubscriptionNotFound
+		}
+		return nil
+	})
+}
+
+func (s *storage) PutVacancyReaction(ctx context.Context, r *model.ChatVacancyReaction) error {
+	query := sanitizeQuery(
+		`INSERT INTO chat_vacancy_reactions(
+            subscription_id,
+            chat_id,
+            vacancy_id,
+            employer_id,
+            reaction,
+            created_at
+        ) SELECT $1, $2, $3, $4, $5, $6
+            WHERE EXISTS (SELECT 1 FROM chat_subscriptions WHERE subscription_id = $1 AND chat_id = $2)
+        ON CONFLICT (chat_id, vacancy_id) DO UPDATE SET
+            subscription_id = EXCLUDED.subscription_id,
+            reaction = EXCLUDED.reaction,
+            created_at = EXCLUDED.created_at`)
 
 	return retries.DoWithRetries(retryCount, retryWait, func() error {
-		if _, err := s.client.Exec(ctx, query, postgres.SingleQuote(subID)); err != nil {
+		tag, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				r.SubscriptionID,
+				r.ChatID,
+				r.VacancyID,
+				r.EmployerID,
+				string(r.Reaction),
+				r.CreatedAt,
+			)...,
+		)
+		if err != nil {
 			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
 		}
+		// subscription was deleted or belongs to another chat
+		if tag.RowsAffected() == 0 {
+			return ErrSubscriptionNotFound
+		}
 		return nil
 	})
 }
 
+// PutHiddenEmployer hides employer for chat and returns false if employer was already hidden.
+func (s *storage) PutHiddenEmployer(ctx context.Context, e *model.ChatHiddenEmployer) (bool, error) {
+	query := sanitizeQuery(
+		`INSERT INTO chat_hidden_employers(
+            chat_id,
+            employer_id,
+            vacancy_id,
+            created_at
+        ) VALUES ($1, $2, $3, $4)
+        ON CONFLICT (chat_id, employer_id) DO NOTHING`)
+
+	var hidden bool
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		tag, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				e.ChatID,
+				e.EmployerID,
+				e.VacancyID,
+				e.CreatedAt,
+			)...,
+		)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		hidden = tag.RowsAffected() > 0
+		return nil
+
+	}); err != nil {
+		return false, err
+	}
+	return hidden, nil
+}
+
+// DeleteHiddenEmployer shows employer for chat again and returns false if employer was not hidden.
+func (s *storage) DeleteHiddenEmployer(ctx context.Context, chatID int64, employerID string) (bool, error) {
+	query := sanitizeQuery(
+		`DELETE FROM chat_hidden_employers
+        WHERE chat_id = $1 AND employer_id = $2`)
+
+	var deleted bool
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		tag, err := s.client.Exec(ctx, query, postgres.MultiQuote(chatID, employerID)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		deleted = tag.RowsAffected() > 0
+		return nil
+
+	}); err != nil {
+		return false, err
+	}
+	return deleted, nil
+}
+
+func (s *storage) HiddenEmployers(ctx context.Context, chatID int64) ([]string, error) {
+	query := sanitizeQuery(
+		`SELECT
+            employer_id
+        FROM chat_hidden_employers WHERE chat_id = $1`)
+
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err = s.client.Query(ctx, query, postgres.SingleQuote(chatID))
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+	var (
+		employers []string
+		ok        bool
+	)
+	for {
+		var employerID string
+
+		if ok, err = scanQueriedRow(rows, &employerID); err != nil {
+			return nil, fmt.Errorf("cannot scan queried row: %s: %v", query, err)
+		}
+		if !ok {
+			break
+		}
+		employers = append(employers, employerID)
+	}
+	return employers, nil
+}
+
+func (s *storage) SubscriptionReactionsCount(ctx context.Context, chatID, subID int64, reaction model.VacancyReaction) (int64, error) {
+	query := sanitizeQuery(
+		`SELECT
+            COUNT(*)
+        FROM chat_vacancy_reactions WHERE chat_id = $1 AND subscription_id = $2 AND reaction = $3`)
+
+	var count int64
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err := s.client.QueryRow(ctx, query,
+			postgres.MultiQuote(
+				chatID,
+				subID,
+				string(reaction),
+			)...,
+		).Scan(&count); err != nil {
+			return fmt.Errorf("cannot do postgres query row: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *storage) VacancyReaction(ctx context.Context, chatID int64, vacancyID string) (model.VacancyReaction, error) {
+	query := sanitizeQuery(
+		`SELECT
+            reaction
+        FROM chat_vacancy_reactions WHERE chat_id = $1 AND vacancy_id = $2`)
+
+	var (
+		rows     pgx.Rows
+		err      error
+		reaction string
+	)
+	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err = s.client.Query(ctx, query, postgres.MultiQuote(chatID, vacancyID)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	if _, err = scanQueriedRow(rows, &reaction); err != nil {
+		return "", fmt.Errorf("cannot scan queried row: %s: %v", query, err)
+	}
+	return model.VacancyReaction(reaction), nil
+}
+
+func (s *storage) PutFavoriteVacancy(ctx context.Context, fav *model.ChatFavoriteVacancy) error {
+	query := sanitizeQuery(
+		`INSERT INTO chat_favorite_vacancies(
+            chat_id,
+            vacancy_id,
+            name,
+            employer_id,
+            employer_name,
+            area,
+            salary_from,
+            salary_to,
+            salary_currency,
+            salary_gross,
+            url,
+            archived,
+            created_at,
+            updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+        ON CONFLICT (chat_id, vacancy_id) DO UPDATE SET
+            name = EXCLUDED.name,
+            employer_id = EXCLUDED.employer_id,
+            employer_name = EXCLUDED.employer_name,
+            area = EXCLUDED.area,
+            salary_from = EXCLUDED.salary_from,
+            salary_to = EXCLUDED.salary_to,
+            salary_currency = EXCLUDED.salary_currency,
+            salary_gross = EXCLUDED.salary_gross,
+            url = EXCLUDED.url,
+            archived = EXCLUDED.archived,
+            updated_at = EXCLUDED.updated_at`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				fav.ChatID,
+				fav.VacancyID,
+				fav.Name,
+				fav.EmployerID,
+				fav.EmployerName,
+				fav.Area,
+				fav.SalaryFrom,
+				fav.SalaryTo,
+				fav.SalaryCurrency,
+				fav.SalaryGross,
+				fav.Url,
+				fav.Archived,
+				fav.CreatedAt,
+				fav.UpdatedAt,
+			)...,
+		); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+const favoriteVacancyFields = `
+            favorite_id,
+            chat_id,
+            vacancy_id,
+            name,
+            employer_id,
+            employer_name,
+            area,
+            salary_from,
+            salary_to,
+            salary_currency,
+            salary_gross,
+            url,
+            archived,
+            created_at,
+            updated_at`
+
+func scanFavoriteVacancy(rows pgx.Rows, fav *model.ChatFavoriteVacancy) (bool, error) {
+	return scanQueriedRow(rows,
+		&fav.FavoriteID,
+		&fav.ChatID,
+		&fav.VacancyID,
+		&fav.Name,
+		&fav.EmployerID,
+		&fav.EmployerName,
+		&fav.Area,
+		&fav.SalaryFrom,
+		&fav.SalaryTo,
+		&fav.SalaryCurrency,
+		&fav.SalaryGross,
+		&fav.Url,
+		&fav.Archived,
+		&fav.CreatedAt,
+		&fav.UpdatedAt,
+	)
+}
+
+func (s *storage) FavoriteVacancy(ctx context.Context, chatID int64, favID int64) (*model.ChatFavoriteVacancy, error) {
+	query := sanitizeQuery(
+		`SELECT` + favoriteVacancyFields + `
+        FROM chat_favorite_vacancies WHERE chat_id = $1 AND favorite_id = $2`)
+
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err = s.client.Query(ctx, query, postgres.MultiQuote(chatID, favID)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fav := &model.ChatFavoriteVacancy{}
+
+	ok, err := scanFavoriteVacancy(rows, fav)
+	if err != nil {
+		return nil, fmt.Errorf("cannot scan queried row: %s: %v", query, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return fav, nil
+}
+
+func (s *storage) FavoriteVacancies(ctx context.Context, chatID int64, offset, limit int64) ([]*model.ChatFavoriteVacancy, error) {
+	query := sanitizeQuery(
+		`SELECT` + favoriteVacancyFields + `
+        FROM chat_favorite_vacancies WHERE chat_id = $1
+        ORDER BY created_at DESC, favorite_id DESC
+        OFFSET $2 LIMIT $3`)
+
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err = s.client.Query(ctx, query, postgres.MultiQuote(chatID, offset, limit)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+	var (
+		favs []*model.ChatFavoriteVacancy
+		ok   bool
+	)
+	for {
+		fav := &model.ChatFavoriteVacancy{}
+
+		if ok, err = scanFavoriteVacancy(rows, fav); err != nil {
+			return nil, fmt.Errorf("cannot scan queried row: %s: %v", query, err)
+		}
+		if !ok {
+			break
+		}
+		favs = append(favs, fav)
+	}
+	return favs, nil
+}
+
+// ActiveFavoriteVacancies returns batch of not archived favorite vacancies
+// which were not checked after checkedBefore, least recently checked first.
+func (s *storage) ActiveFavoriteVacancies(ctx context.Context, checkedBefore time.Time, limit int64, callback func(fav *model.ChatFavoriteVacancy)) error {
+	query := sanitizeQuery(
+		`SELECT` + favoriteVacancyFields + `
+        FROM chat_favorite_vacancies WHERE archived = false AND (checked_at IS NULL OR checked_at < $1)
+        ORDER BY checked_at NULLS FIRST
+        LIMIT $2`)
+
+	var (
+		rows pgx.Rows
+		err  error
+		ok   bool
+	)
+	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err = s.client.Query(ctx, query, postgres.MultiQuote(checkedBefore, limit)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return err
+	}
+	for {
+		fav := &model.ChatFavoriteVacancy{}
+
+		if ok, err = scanFavoriteVacancy(rows, fav); err != nil {
+			return fmt.Errorf("cannot scan queried row: %v", err)
+		}
+		if !ok {
+			break
+		}
+		callback(fav)
+	}
+	return nil
+}
+
+// SetFavoriteVacanciesChecked sets check time of favorite vacancies, so they are checked again after check interval.
+func (s *storage) SetFavoriteVacanciesChecked(ctx context.Context, favIDs []int64, checkedAt time.Time) error {
+	query := sanitizeQuery(
+		`UPDATE chat_favorite_vacancies
+            SET checked_at = $2
+        WHERE favorite_id = ANY($1)`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query,
+			// ids array passed as is, quote supports scalar values only
+			favIDs,
+			postgres.SingleQuote(checkedAt),
+		); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+func (s *storage) FavoriteVacanciesCount(ctx context.Context, chatID int64) (int64, error) {
+	query := sanitizeQuery(
+		`SELECT
+            COUNT(*)
+        FROM chat_favorite_vacancies WHERE chat_id = $1`)
+
+	var count int64
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err := s.client.QueryRow(ctx, query, postgres.SingleQuote(chatID)).Scan(&count); err != nil {
+			return fmt.Errorf("cannot do postgres query row: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *storage) FavoriteVacancyExist(ctx context.Context, chatID int64, vacancyID string) (bool, error) {
+	query := sanitizeQuery(
+		`SELECT EXISTS(
+            SELECT 1 FROM chat_favorite_vacancies WHERE chat_id = $1 AND vacancy_id = $2
+        )`)
+
+	var exist bool
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err := s.client.QueryRow(ctx, query, postgres.MultiQuote(chatID, vacancyID)...).Scan(&exist); err != nil {
+			return fmt.Errorf("cannot do postgres query row: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return false, err
+	}
+	return exist, nil
+}
+
+func (s *storage) DeleteFavoriteVacancy(ctx context.Context, chatID int64, favID int64) error {
+	query := sanitizeQuery(
+		`DELETE
+            FROM chat_favorite_vacancies
+        WHERE chat_id = $1 AND favorite_id = $2`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query, postgres.MultiQuote(chatID, favID)...); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+// SetChatSubscriptionsActive sets chat subscriptions active. First deactivation time is kept for retention.
+func (s *storage) SetChatSubscriptionsActive(ctx context.Context, chatID int64, active bool) error {
+	query := sanitizeQuery(
+		`UPDATE chat_subscriptions
+            SET active = $2,
+            deactivated_at = CASE WHEN $2 THEN NULL ELSE COALESCE(deactivated_at, $3) END
+        WHERE chat_id = $1`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query, postgres.MultiQuote(chatID, active, utils.NowTimeUTC())...); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+// DeleteInactiveSubscriptions deletes batch of subscriptions deactivated before passed time and returns count of deleted rows.
+// Sent vacancies, reactions and delivery jobs of subscriptions are deleted by cascade.
+func (s *storage) DeleteInactiveSubscriptions(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	query := sanitizeQuery(
+		`DELETE FROM chat_subscriptions WHERE subscription_id IN (
+            SELECT subscription_id FROM chat_subscriptions WHERE active = false AND deactivated_at < $1
+            LIMIT $2
+        )`)
+
+	var deleted int64
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		tag, err := s.client.Exec(ctx, query, postgres.MultiQuote(before, limit)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		deleted = tag.RowsAffected()
+		return nil
+
+	}); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+func (s *storage) MigrateChat(ctx context.Context, chatID, newChatID int64) error {
+	queries := []string{
+		sanitizeQuery(`UPDATE chat_subscriptions SET chat_id = $2 WHERE chat_id = $1`),
+		sanitizeQuery(`UPDATE chat_vacancy_reactions SET chat_id = $2 WHERE chat_id = $1`),
+		sanitizeQuery(`UPDATE chat_favorite_vacancies SET chat_id = $2 WHERE chat_id = $1`),
+		sanitizeQuery(`UPDATE chat_hidden_employers SET chat_id = $2 WHERE chat_id = $1`),
+		sanitizeQuery(`UPDATE delivery_jobs SET chat_id = $2 WHERE chat_id = $1`),
+		sanitizeQuery(`UPDATE chat_sent_vacancies SET chat_id = $2 WHERE chat_id = $1`),
+	}
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		return s.client.BeginTxFunc(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			for _, query := range queries {
+				if _, err := tx.Exec(ctx, query, postgres.MultiQuote(chatID, newChatID)...); err != nil {
+					return fmt.Errorf("cannot do postgres tx exec: %s: %v", query, err)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// LeaseDeliveryJobs leases available jobs and jobs with expired lease.
+// Leased jobs are invisible for other workers until leasedUntil.
+func (s *storage) LeaseDeliveryJobs(ctx context.Context, limit int64, leasedUntil time.Time) ([]*model.DeliveryJob, error) {
+	query := sanitizeQuery(
+		`UPDATE delivery_jobs SET
+            status = $1,
+            attempts = attempts + 1,
+            leased_until = $2,
+            updated_at = $3
+        WHERE job_id IN (
+            SELECT job_id FROM delivery_jobs
+            WHERE (status = $4 AND available_at <= $3) OR (status = $1 AND leased_until < $3)
+            ORDER BY available_at, job_id
+            LIMIT $5
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING
+            job_id,
+            COALESCE(sent_id, 0),
+            subscription_id,
+            chat_id,
+            vacancy_id,
+            payload,
+            status,
+            attempts,
+            max_attempts,
+            COALESCE(last_error, ''),
+            available_at,
+            leased_until,
+            created_at,
+            updated_at`)
+
+	var jobs []*model.DeliveryJob
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err := s.client.Query(ctx, query,
+			postgres.MultiQuote(
+				string(model.DeliveryJobLeased),
+				leasedUntil,
+				utils.NowTimeUTC(),
+				string(model.DeliveryJobPending),
+				limit,
+			)...,
+		)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		defer rows.Close()
+
+		jobs = nil
+
+		for rows.Next() {
+			var (
+				job     = &model.DeliveryJob{}
+				payload string
+				status  string
+			)
+			if err = rows.Scan(
+				&job.JobID,
+				&job.SentID,
+				&job.SubscriptionID,
+				&job.ChatID,
+				&job.VacancyID,
+				&payload,
+				&status,
+				&job.Attempts,
+				&job.MaxAttempts,
+				&job.LastError,
+				&job.AvailableAt,
+				&job.LeasedUntil,
+				&job.CreatedAt,
+				&job.UpdatedAt,
+			); err != nil {
+				return fmt.Errorf("cannot scan queried row: %s: %v", query, err)
+			}
+			job.Payload = []byte(payload)
+			job.Status = model.DeliveryJobStatus(status)
+
+			jobs = append(jobs, job)
+		}
+		return rows.Err()
+
+	}); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ExtendDeliveryJobsLease prolongs lease of jobs which are still leased, so jobs in flight are not leased again.
+func (s *storage) ExtendDeliveryJobsLease(ctx context.Context, jobIDs []int64, leasedUntil time.Time) error {
+	query := sanitizeQuery(
+		`UPDATE delivery_jobs SET
+            leased_until = $3,
+            updated_at = $4
+        WHERE job_id = ANY($1) AND status = $2`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query,
+			// ids array passed as is, quote supports scalar values only
+			jobIDs,
+			postgres.SingleQuote(string(model.DeliveryJobLeased)),
+			postgres.SingleQuote(leasedUntil),
+			postgres.SingleQuote(utils.NowTimeUTC()),
+		); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+// FailDeliveryJob returns job to pending until retryAt or moves it to dead letters if attempts exhausted.
+func (s *storage) FailDeliveryJob(ctx context.Context, jobID int64, retryAt time.Time, reason string) error {
+	query := sanitizeQuery(
+		`UPDATE delivery_jobs SET
+            status = CASE WHEN attempts >= max_attempts THEN $2 ELSE $3 END,
+            available_at = $4,
+            leased_until = NULL,
+            last_error = $5,
+            updated_at = $6
+        WHERE job_id = $1`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				jobID,
+				string(model.DeliveryJobDead),
+				string(model.DeliveryJobPending),
+				retryAt,
+				reason,
+				utils.NowTimeUTC(),
+			)...,
+		); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+// PostponeDeliveryJob returns job to pending without counting lease as attempt.
+func (s *storage) PostponeDeliveryJob(ctx context.Context, jobID int64, availableAt time.Time) error {
+	query := sanitizeQuery(
+		`UPDATE delivery_jobs SET
+            status = $2,
+            attempts = GREATEST(attempts - 1, 0),
+            available_at = $3,
+            leased_until = NULL,
+            updated_at = $4
+        WHERE job_id = $1`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				jobID,
+				string(model.DeliveryJobPending),
+				availableAt,
+				utils.NowTimeUTC(),
+			)...,
+		); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+func (s *storage) DeadDeliveryJob(ctx context.Context, jobID int64, reason string) error {
+	query := sanitizeQuery(
+		`UPDATE delivery_jobs SET
+            status = $2,
+            leased_until = NULL,
+            last_error = $3,
+            updated_at = $4
+        WHERE job_id = $1`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				jobID,
+				string(model.DeliveryJobDead),
+				reason,
+				utils.NowTimeUTC(),
+			)...,
+		); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+// DeleteFinishedDeliveryJobs deletes batch of done and dead jobs updated before passed time and returns count of deleted rows.
+func (s *storage) DeleteFinishedDeliveryJobs(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	query := sanitizeQuery(
+		`DELETE FROM delivery_jobs WHERE job_id IN (
+            SELECT job_id FROM delivery_jobs WHERE status IN ($1, $2) AND updated_at < $3
+            LIMIT $4
+        )`)
+
+	var deleted int64
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		tag, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				string(model.DeliveryJobDone),
+				string(model.DeliveryJobDead),
+				before,
+				limit,
+			)...,
+		)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		deleted = tag.RowsAffected()
+		return nil
+
+	}); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+func (s *storage) DeliveryJobsCount(ctx context.Context, status model.DeliveryJobStatus) (int64, error) {
+	query := sanitizeQuery(
+		`SELECT
+            COUNT(*)
+        FROM delivery_jobs WHERE status = $1`)
+
+	var count int64
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err := s.client.QueryRow(ctx, query, postgres.SingleQuote(string(status))).Scan(&count); err != nil {
+			return fmt.Errorf("cannot do postgres query row: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *storage) PutInstanceHeartbeat(ctx context.Context, instanceID string, startedAt, heartbeatAt time.Time) error {
+	query := sanitizeQuery(
+		`INSERT INTO instances(
+            instance_id,
+            started_at,
+            heartbeat_at
+        ) VALUES ($1, $2, $3)
+        ON CONFLICT (instance_id) DO UPDATE SET heartbeat_at = EXCLUDED.heartbeat_at`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query,
+			postgres.MultiQuote(
+				instanceID,
+				startedAt,
+				heartbeatAt,
+			)...,
+		); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+// LiveInstances returns ids of instances which sent heartbeat since passed time.
+func (s *storage) LiveInstances(ctx context.Context, since time.Time) ([]string, error) {
+	query := sanitizeQuery(
+		`SELECT
+            instance_id
+        FROM instances WHERE heartbeat_at >= $1
+        ORDER BY instance_id`)
+
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		rows, err = s.client.Query(ctx, query, postgres.SingleQuote(since))
+		if err != nil {
+			return fmt.Errorf("cannot do postgres query: %s: %v", query, err)
+		}
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+	var (
+		ids []string
+		ok  bool
+	)
+	for {
+		var id string
+
+		if ok, err = scanQueriedRow(rows, &id); err != nil {
+			return nil, fmt.Errorf("cannot scan queried row: %s: %v", query, err)
+		}
+		if !ok {
+			break
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *storage) DeleteInstance(ctx context.Context, instanceID string) error {
+	query := sanitizeQuery(
+		`DELETE FROM instances WHERE instance_id = $1`)
+
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if _, err := s.client.Exec(ctx, query, postgres.SingleQuote(instanceID)); err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		return nil
+	})
+}
+
+// DeleteStaleInstances deletes batch of instances with heartbeat before passed time and returns count of deleted rows.
+func (s *storage) DeleteStaleInstances(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	query := sanitizeQuery(
+		`DELETE FROM instances WHERE instance_id IN (
+            SELECT instance_id FROM instances WHERE heartbeat_at < $1
+            LIMIT $2
+        )`)
+
+	var deleted int64
+
+	if err := retries.DoWithRetries(retryCount, retryWait, func() error {
+		tag, err := s.client.Exec(ctx, query, postgres.MultiQuote(before, limit)...)
+		if err != nil {
+			return fmt.Errorf("cannot do postgres exec: %s: %v", query, err)
+		}
+		deleted = tag.RowsAffected()
+		return nil
+
+	}); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// pollHash maps unsigned hash to signed postgres bigint keeping hashes order, so hash ranges are compared in query.
+func pollHash(hash uint64) int64 {
+	return int64(hash ^ 1<<63)
+}
+
 func scanQueriedRow(rows pgx.Rows, fields ...any) (bool, error) {
 	var hasRow bool
 	if rows.Next() {
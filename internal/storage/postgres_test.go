@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"main/pkg/hashring"
+	"math"
+	"testing"
+)
+
+func TestPollHashKeepsOrder(t *testing.T) {
+	hashes := []uint64{0, 1, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64 - 1, math.MaxUint64}
+
+	for i := 1; i < len(hashes); i++ {
+		if pollHash(hashes[i-1]) >= pollHash(hashes[i]) {
+			t.Fatalf("pollHash(%d) = %d is not less than pollHash(%d) = %d",
+				hashes[i-1], pollHash(hashes[i-1]), hashes[i], pollHash(hashes[i]))
+		}
+	}
+	if pollHash(0) != math.MinInt64 || pollHash(math.MaxUint64) != math.MaxInt64 {
+		t.Fatalf("pollHash does not map hash space bounds to bigint bounds")
+	}
+}
+
+// TestPollHashBackfill keeps values computed by poll_hash function of migrations/backfill.sql.
+func TestPollHashBackfill(t *testing.T) {
+	tests := []struct {
+		key  string
+		want int64
+	}{
+		{key: "", want: 5472609002491880229},
+		{key: "1:noExperience:golang", want: -622157202823624333},
+		{key: "113:between1And3:Разработчик Go", want: 7189953376654063307},
+	}
+	for _, tt := range tests {
+		if got := pollHash(hashring.Hash(tt.key)); got != tt.want {
+			t.Fatalf("pollHash of %q = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
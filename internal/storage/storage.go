@@ -2,15 +2,58 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"main/internal/model"
+	"main/pkg/hashring"
+	"time"
 )
 
+// ErrSubscriptionNotFound returned when subscription does not exist or belongs to another chat.
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
 type Storage interface {
 	ChatSubscriptionsSets(ctx context.Context, callback func(subSet *model.ChatSubscriptionSet)) error
 	ChatsSubscriptions(ctx context.Context, callback func(sub *model.ChatSubscription)) error
 	ChatSubscriptions(ctx context.Context, chatID int64) ([]*model.ChatSubscription, error)
 	PutChatSubscription(ctx context.Context, sub *model.ChatSubscription) error
-	SentVacancies(ctx context.Context) ([]*model.ChatSentVacancy, error)
-	PutSentVacancy(ctx context.Context, sentVacancy *model.ChatSentVacancy) error
-	DeleteChatSubscription(ctx context.Context, subID int64) error
+	DueSubscriptions(ctx context.Context, until time.Time, ranges []hashring.Range, limit int64) ([]*model.ChatSubscription, error)
+	SetSubscriptionPoll(ctx context.Context, subID int64, nextPollAt time.Time, interval time.Duration) error
+	SentVacancyIDs(ctx context.Context, chatID int64, vacancyIDs []string) ([]string, error)
+	ChatSentVacancyIDs(ctx context.Context, chatID int64, limit int64) ([]string, error)
+	DeleteSentVacancies(ctx context.Context, before time.Time, limit int64) (int64, error)
+	ActiveSentVacancies(ctx context.Context, since, checkedBefore time.Time, limit int64, callback func(sv *model.ChatSentVacancy, sub *model.ChatSubscription)) error
+	SetSentVacanciesChecked(ctx context.Context, sentIDs []int64, checkedAt time.Time) error
+	ArchiveSentVacancy(ctx context.Context, sentID int64) error
+	DeleteChatSubscription(ctx context.Context, chatID, subID int64) error
+	SetChatSubscriptionsActive(ctx context.Context, chatID int64, active bool) error
+	DeleteInactiveSubscriptions(ctx context.Context, before time.Time, limit int64) (int64, error)
+	ClaimSentVacancy(ctx context.Context, sentVacancy *model.ChatSentVacancy, job *model.DeliveryJob) (bool, error)
+	MarkVacancySent(ctx context.Context, job *model.DeliveryJob, messageID int64) error
+	SentVacancyStatus(ctx context.Context, sentID int64) (model.SentVacancyStatus, int64, error)
+	LeaseDeliveryJobs(ctx context.Context, limit int64, leasedUntil time.Time) ([]*model.DeliveryJob, error)
+	ExtendDeliveryJobsLease(ctx context.Context, jobIDs []int64, leasedUntil time.Time) error
+	FailDeliveryJob(ctx context.Context, jobID int64, retryAt time.Time, reason string) error
+	PostponeDeliveryJob(ctx context.Context, jobID int64, availableAt time.Time) error
+	DeadDeliveryJob(ctx context.Context, jobID int64, reason string) error
+	DeliveryJobsCount(ctx context.Context, status model.DeliveryJobStatus) (int64, error)
+	DeleteFinishedDeliveryJobs(ctx context.Context, before time.Time, limit int64) (int64, error)
+	MigrateChat(ctx context.Context, chatID, newChatID int64) error
+	PutInstanceHeartbeat(ctx context.Context, instanceID string, startedAt, heartbeatAt time.Time) error
+	LiveInstances(ctx context.Context, since time.Time) ([]string, error)
+	DeleteInstance(ctx context.Context, instanceID string) error
+	DeleteStaleInstances(ctx context.Context, before time.Time, limit int64) (int64, error)
+	PutVacancyReaction(ctx context.Context, reaction *model.ChatVacancyReaction) error
+	PutHiddenEmployer(ctx context.Context, e *model.ChatHiddenEmployer) (bool, error)
+	DeleteHiddenEmployer(ctx context.Context, chatID int64, employerID string) (bool, error)
+	HiddenEmployers(ctx context.Context, chatID int64) ([]string, error)
+	SubscriptionReactionsCount(ctx context.Context, chatID, subID int64, reaction model.VacancyReaction) (int64, error)
+	VacancyReaction(ctx context.Context, chatID int64, vacancyID string) (model.VacancyReaction, error)
+	PutFavoriteVacancy(ctx context.Context, fav *model.ChatFavoriteVacancy) error
+	FavoriteVacancy(ctx context.Context, chatID int64, favID int64) (*model.ChatFavoriteVacancy, error)
+	ActiveFavoriteVacancies(ctx context.Context, checkedBefore time.Time, limit int64, callback func(fav *model.ChatFavoriteVacancy)) error
+	SetFavoriteVacanciesChecked(ctx context.Context, favIDs []int64, checkedAt time.Time) error
+	FavoriteVacancies(ctx context.Context, chatID int64, offset, limit int64) ([]*model.ChatFavoriteVacancy, error)
+	FavoriteVacanciesCount(ctx context.Context, chatID int64) (int64, error)
+	FavoriteVacancyExist(ctx context.Context, chatID int64, vacancyID string) (bool, error)
+	DeleteFavoriteVacancy(ctx context.Context, chatID int64, favID int64) error
 }
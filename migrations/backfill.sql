@@ -0,0 +1,51 @@
+-- Applied to databases created before schema.sql changes, new databases are created by schema.sql.
+
+ALTER TABLE chat_subscriptions
+    ADD COLUMN IF NOT EXISTS urgent BOOLEAN DEFAULT false,
+    ADD COLUMN IF NOT EXISTS next_poll_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS poll_interval BIGINT DEFAULT 0;
+
+-- subscriptions created before adaptive polling are due right away
+UPDATE chat_subscriptions SET next_poll_at = NOW() AT TIME ZONE 'UTC' WHERE next_poll_at IS NULL;
+
+ALTER TABLE chat_subscriptions
+    ALTER COLUMN next_poll_at SET DEFAULT (NOW() AT TIME ZONE 'UTC'),
+    ALTER COLUMN next_poll_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS chat_subscriptions_poll_idx ON chat_subscriptions (next_poll_at) WHERE active = true;
+
+-- poll_hash mirrors storage pollHash(hashring.Hash(pollGroupKey)): FNV-1a 64 of group key shifted to bigint range,
+-- so backfilled subscriptions fall into same shards as subscriptions of their group stored by bot
+CREATE OR REPLACE FUNCTION poll_hash(key TEXT) RETURNS BIGINT AS
+$$
+DECLARE
+    bytes BYTEA   := CONVERT_TO(key, 'UTF8');
+    hash  NUMERIC := 14695981039346656037;
+BEGIN
+    FOR i IN 0 .. LENGTH(bytes) - 1
+        LOOP
+            hash := hash - MOD(hash, 256) + (MOD(hash, 256)::INT # GET_BYTE(bytes, i));
+            hash := MOD(hash * 1099511628211, 18446744073709551616);
+        END LOOP;
+    RETURN (hash - 9223372036854775808)::BIGINT;
+END
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+ALTER TABLE chat_subscriptions
+    ADD COLUMN IF NOT EXISTS poll_hash BIGINT;
+
+-- subscriptions without hash are out of every shard range and never polled
+UPDATE chat_subscriptions
+SET poll_hash = poll_hash(COALESCE(area, '') || ':' || COALESCE(experience, '') || ':' || COALESCE(keywords, ''))
+WHERE poll_hash IS NULL;
+
+ALTER TABLE chat_subscriptions
+    ALTER COLUMN poll_hash SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS chat_subscriptions_poll_hash_idx ON chat_subscriptions (poll_hash, next_poll_at) WHERE active = true;
+
+-- favorites are checked in batches by check time, not checked favorites first
+ALTER TABLE chat_favorite_vacancies
+    ADD COLUMN IF NOT EXISTS checked_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS chat_favorite_vacancies_checked_idx ON chat_favorite_vacancies (checked_at NULLS FIRST) WHERE archived = false;
@@ -6,16 +6,117 @@ CREATE TABLE chat_subscriptions
     area            VARCHAR(32),
     keywords        VARCHAR(256),
     experience      VARCHAR(128),
+    active          BOOLEAN DEFAULT true,
+    urgent          BOOLEAN DEFAULT false,
+    next_poll_at    TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC'),
+    poll_interval   BIGINT DEFAULT 0,
+    poll_hash       BIGINT NOT NULL,
     created_at      TIMESTAMP,
+    deactivated_at  TIMESTAMP,
     CONSTRAINT unique_subscription UNIQUE (chat_id, area, keywords, experience)
 );
 
+CREATE INDEX chat_subscriptions_poll_idx ON chat_subscriptions (next_poll_at) WHERE active = true;
+
+CREATE INDEX chat_subscriptions_poll_hash_idx ON chat_subscriptions (poll_hash, next_poll_at) WHERE active = true;
+
+CREATE INDEX chat_subscriptions_deactivated_idx ON chat_subscriptions (deactivated_at) WHERE active = false;
+
 CREATE TABLE chat_sent_vacancies
 (
     sent_id         SERIAL PRIMARY KEY,
     subscription_id INT REFERENCES chat_subscriptions (subscription_id) ON DELETE CASCADE,
+    chat_id         BIGINT,
     vacancy_id      VARCHAR(128),
-    created_at      TIMESTAMP
+    vacancy_name    VARCHAR(512),
+    message_id      BIGINT,
+    status          VARCHAR(16) DEFAULT 'sent',
+    archived        BOOLEAN DEFAULT false,
+    created_at      TIMESTAMP,
+    sent_at         TIMESTAMP,
+    checked_at      TIMESTAMP,
+    CONSTRAINT unique_sent_vacancy UNIQUE (subscription_id, vacancy_id)
+);
+
+CREATE UNIQUE INDEX chat_sent_vacancies_chat_idx ON chat_sent_vacancies (chat_id, vacancy_id);
+
+CREATE INDEX chat_sent_vacancies_created_idx ON chat_sent_vacancies (created_at);
+
+CREATE INDEX chat_sent_vacancies_checked_idx ON chat_sent_vacancies (checked_at NULLS FIRST) WHERE archived = false;
+
+CREATE TABLE chat_vacancy_reactions
+(
+    reaction_id     SERIAL PRIMARY KEY,
+    subscription_id INT REFERENCES chat_subscriptions (subscription_id) ON DELETE CASCADE,
+    chat_id         BIGINT,
+    vacancy_id      VARCHAR(128),
+    employer_id     VARCHAR(128),
+    reaction        VARCHAR(32),
+    created_at      TIMESTAMP,
+    CONSTRAINT unique_reaction UNIQUE (chat_id, vacancy_id)
+);
+
+CREATE TABLE chat_hidden_employers
+(
+    hidden_id   SERIAL PRIMARY KEY,
+    chat_id     BIGINT,
+    employer_id VARCHAR(128),
+    vacancy_id  VARCHAR(128),
+    created_at  TIMESTAMP,
+    CONSTRAINT unique_hidden_employer UNIQUE (chat_id, employer_id)
+);
+
+CREATE TABLE chat_favorite_vacancies
+(
+    favorite_id     SERIAL PRIMARY KEY,
+    chat_id         BIGINT,
+    vacancy_id      VARCHAR(128),
+    name            VARCHAR(512),
+    employer_id     VARCHAR(128),
+    employer_name   VARCHAR(512),
+    area            VARCHAR(128),
+    salary_from     BIGINT,
+    salary_to       BIGINT,
+    salary_currency VARCHAR(16),
+    salary_gross    BOOLEAN,
+    url             VARCHAR(512),
+    archived        BOOLEAN DEFAULT false,
+    created_at      TIMESTAMP,
+    updated_at      TIMESTAMP,
+    checked_at      TIMESTAMP,
+    CONSTRAINT unique_favorite UNIQUE (chat_id, vacancy_id)
+);
+
+CREATE INDEX chat_favorite_vacancies_checked_idx ON chat_favorite_vacancies (checked_at NULLS FIRST) WHERE archived = false;
+
+CREATE TABLE delivery_jobs
+(
+    job_id          BIGSERIAL PRIMARY KEY,
+    sent_id         INT REFERENCES chat_sent_vacancies (sent_id) ON DELETE CASCADE,
+    subscription_id INT REFERENCES chat_subscriptions (subscription_id) ON DELETE CASCADE,
+    chat_id         BIGINT,
+    vacancy_id      VARCHAR(128),
+    payload         TEXT,
+    status          VARCHAR(16),
+    attempts        INT DEFAULT 0,
+    max_attempts    INT,
+    last_error      TEXT,
+    available_at    TIMESTAMP,
+    leased_until    TIMESTAMP,
+    created_at      TIMESTAMP,
+    updated_at      TIMESTAMP,
+    CONSTRAINT unique_delivery_job UNIQUE (subscription_id, vacancy_id)
+);
+
+CREATE INDEX delivery_jobs_available_idx ON delivery_jobs (status, available_at);
+
+CREATE INDEX delivery_jobs_finished_idx ON delivery_jobs (updated_at) WHERE status IN ('done', 'dead');
+
+CREATE TABLE instances
+(
+    instance_id  VARCHAR(128) PRIMARY KEY,
+    heartbeat_at TIMESTAMP,
+    started_at   TIMESTAMP
 );
 
 SELECT DISTINCT subscriptions_ids,
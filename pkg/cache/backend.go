@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	pkgredis "main/pkg/redis"
+	"main/pkg/validation"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	MemoryBackend = "memory"
+	RedisBackend  = "redis"
+)
+
+type Config struct {
+	Backend string           `yaml:"backend"`
+	Prefix  string           `yaml:"prefix"`
+	Redis   *pkgredis.Config `yaml:"redis"`
+}
+
+// Backend creates caches of configured backend. Memory backend is used by default.
+type Backend struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewBackend(ctx context.Context, config *Config) (*Backend, error) {
+	if config == nil {
+		return &Backend{}, nil
+	}
+	switch backend := config.Backend; backend {
+	case "", MemoryBackend:
+		return &Backend{}, nil
+
+	case RedisBackend:
+		if config.Redis == nil {
+			return nil, fmt.Errorf("redis config not specified")
+		}
+		if err := validation.ValidateStructFields(config.Redis); err != nil {
+			return nil, fmt.Errorf("cannot validate redis config: %v", err)
+		}
+		client, err := pkgredis.NewClient(ctx, config.Redis)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create redis client: %v", err)
+		}
+		return &Backend{
+			client: client,
+			prefix: config.Prefix,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported cache backend: %s", backend)
+	}
+}
+
+// Shared returns true if caches are shared between instances.
+func (b *Backend) Shared() bool {
+	return b.client != nil
+}
+
+// Client returns redis client of backend or nil for memory backend.
+func (b *Backend) Client() redis.UniversalClient {
+	return b.client
+}
+
+// Name returns name with backend prefix, e.g. for redis keys and channels used beside caches.
+func (b *Backend) Name(name string) string {
+	if b.prefix == "" {
+		return name
+	}
+	return fmt.Sprint(b.prefix, ":", name)
+}
+
+// NewBackendMemCache creates named cache of backend. Values are encoded to json for redis backend.
+func NewBackendMemCache[K comparable, T any](b *Backend, name string, opts ...Option) MemCache[K, T] {
+	if !b.Shared() {
+		return NewMemCache[K, T](opts...)
+	}
+	return NewRedisMemCache[K, T](b.client, b.Name(name), JSONCodec[T]{}, opts...)
+}
+
+// NewBackendKeyCache creates named keys set of backend.
+func NewBackendKeyCache[T comparable](b *Backend, name string, opts ...Option) KeyCache[T] {
+	if !b.Shared() {
+		return NewKeyCache[T](opts...)
+	}
+	return NewRedisKeyCache[T](b.client, b.Name(name), opts...)
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// BloomFilter is probabilistic keys set. MayContain has no false negatives for added keys,
+// but may return true for not added keys with false positive rate while count is under capacity.
+type BloomFilter interface {
+	Add(key string)
+	MayContain(key string) bool
+	Count() int
+	// Saturated reports that count exceeded capacity and false positive rate is not guaranteed
+	Saturated() bool
+}
+
+type bloomFilter struct {
+	mtx      sync.RWMutex
+	bits     []uint64
+	size     uint64
+	hashes   uint64
+	count    int
+	capacity int
+}
+
+// NewBloomFilter creates filter sized for capacity keys with falsePositive rate, e.g. 0.01.
+func NewBloomFilter(capacity int, falsePositive float64) BloomFilter {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	if falsePositive <= 0 || falsePositive >= 1 {
+		falsePositive = 0.01
+	}
+	// optimal bits count m = -n*ln(p)/ln(2)^2 and hashes count k = m/n*ln(2)
+	size := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositive) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Round(float64(size) / float64(capacity) * math.Ln2))
+
+	if size < 64 {
+		size = 64
+	}
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &bloomFilter{
+		bits:     make([]uint64, (size+63)/64),
+		size:     size,
+		hashes:   hashes,
+		capacity: capacity,
+	}
+}
+
+func (f *bloomFilter) Add(key string) {
+	h1, h2 := bloomHashes(key)
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+func (f *bloomFilter) MayContain(key string) bool {
+	h1, h2 := bloomHashes(key)
+
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) Count() int {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return f.count
+}
+
+func (f *bloomFilter) Saturated() bool {
+	return f.Count() > f.capacity
+}
+
+// bloomHashes returns two hashes for double hashing, so k hashes are computed from single pass.
+func bloomHashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+
+	h1 := sum & math.MaxUint32
+	h2 := sum >> 32
+
+	// even second hash may cycle over part of bits
+	return h1, h2 | 1
+}
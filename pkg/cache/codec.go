@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Codec encodes cache values stored outside of process memory.
+type Codec[T any] interface {
+	Encode(value T) ([]byte, error)
+	Decode(buf []byte) (T, error)
+}
+
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(value T) ([]byte, error) {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal value to json: %v", err)
+	}
+	return buf, nil
+}
+
+func (JSONCodec[T]) Decode(buf []byte) (T, error) {
+	var value T
+
+	if err := json.Unmarshal(buf, &value); err != nil {
+		return value, fmt.Errorf("cannot unmarshal value from json: %v", err)
+	}
+	return value, nil
+}
@@ -1,53 +1,53 @@
 package cache
 
-import "sync"
+import "time"
 
 type KeyCache[T any] interface {
 	Exist(key T) bool
 	Count() int
 	Put(key T)
+	PutTTL(key T, ttl time.Duration)
 	Delete(key T)
 	Clear()
+	// Sweep removes expired keys and returns count of removed keys
+	Sweep() int
 }
 
-func NewKeyCache[T comparable]() KeyCache[T] {
+func NewKeyCache[T comparable](opts ...Option) KeyCache[T] {
 	return &keyCache[T]{
-		m: map[T]struct{}{},
+		s: newStore[T, struct{}](opts...),
 	}
 }
 
 type keyCache[T comparable] struct {
-	mtx sync.RWMutex
-	m   map[T]struct{}
+	s *store[T, struct{}]
 }
 
 func (c *keyCache[T]) Exist(key T) bool {
-	c.mtx.RLock()
-	defer c.mtx.RUnlock()
-	_, ok := c.m[key]
+	_, ok := c.s.get(key)
 	return ok
 }
 
 func (c *keyCache[T]) Count() int {
-	c.mtx.RLock()
-	defer c.mtx.RUnlock()
-	return len(c.m)
+	return c.s.count()
 }
 
 func (c *keyCache[T]) Put(key T) {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
-	c.m[key] = struct{}{}
+	c.s.put(key, struct{}{}, c.s.options.ttl)
+}
+
+func (c *keyCache[T]) PutTTL(key T, ttl time.Duration) {
+	c.s.put(key, struct{}{}, ttl)
 }
 
 func (c *keyCache[T]) Delete(key T) {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
-	delete(c.m, key)
+	c.s.delete(key)
 }
 
 func (c *keyCache[T]) Clear() {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
-	c.m = map[T]struct{}{}
+	c.s.clear()
+}
+
+func (c *keyCache[T]) Sweep() int {
+	return c.s.sweepExpired()
 }
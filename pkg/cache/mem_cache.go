@@ -1,63 +1,59 @@
 package cache
 
-import "sync"
+import "time"
 
 type MemCache[K comparable, T any] interface {
 	Delete(key K)
 	Exist(key K) bool
+	Count() int
 	Get(key K) T
 	Put(key K, value T)
+	PutTTL(key K, value T, ttl time.Duration)
 	GetPut(key K, value T) T
+	// Sweep removes expired keys and returns count of removed keys
+	Sweep() int
 }
 
-func NewMemCache[K comparable, T any]() MemCache[K, T] {
+func NewMemCache[K comparable, T any](opts ...Option) MemCache[K, T] {
 	return &memCache[K, T]{
-		m: map[K]T{},
+		s: newStore[K, T](opts...),
 	}
 }
 
 type memCache[K comparable, T any] struct {
-	mtx sync.RWMutex
-	m   map[K]T
+	s *store[K, T]
 }
 
 func (c *memCache[K, T]) Delete(key K) {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
-	delete(c.m, key)
+	c.s.delete(key)
 }
 
 func (c *memCache[K, T]) Exist(key K) bool {
-	c.mtx.RLock()
-	defer c.mtx.RUnlock()
-	_, ok := c.m[key]
+	_, ok := c.s.get(key)
 	return ok
 }
 
+func (c *memCache[K, T]) Count() int {
+	return c.s.count()
+}
+
 func (c *memCache[K, T]) Get(key K) T {
-	c.mtx.RLock()
-	defer c.mtx.RUnlock()
-	value, _ := c.m[key]
+	value, _ := c.s.get(key)
 	return value
 }
 
 func (c *memCache[K, T]) Put(key K, value T) {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
-	c.m[key] = value
+	c.s.put(key, value, c.s.options.ttl)
 }
 
-func (c *memCache[K, T]) GetPut(key K, value T) T {
-	c.mtx.RLock()
-	if value, ok := c.m[key]; ok {
-		c.mtx.RUnlock()
-		return value
-	}
-	c.mtx.RUnlock()
+func (c *memCache[K, T]) PutTTL(key K, value T, ttl time.Duration) {
+	c.s.put(key, value, ttl)
+}
 
-	c.mtx.Lock()
-	c.m[key] = value
-	c.mtx.Unlock()
+func (c *memCache[K, T]) GetPut(key K, value T) T {
+	return c.s.getPut(key, value)
+}
 
-	return value
+func (c *memCache[K, T]) Sweep() int {
+	return c.s.sweepExpired()
 }
@@ -0,0 +1,56 @@
+package cache
+
+import "time"
+
+const defaultTimeout = time.Second
+
+type options struct {
+	ttl           time.Duration
+	maxSize       int
+	sweepInterval time.Duration
+	timeout       time.Duration
+}
+
+type Option func(o *options)
+
+// WithTTL sets default expiry for put keys. Keys never expire by default.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithMaxSize limits count of keys, least recently used keys are evicted first.
+func WithMaxSize(size int) Option {
+	return func(o *options) {
+		o.maxSize = size
+	}
+}
+
+// WithSweepInterval sets how often expired keys are removed. Half of ttl by default.
+func WithSweepInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.sweepInterval = interval
+	}
+}
+
+// WithTimeout sets timeout of redis commands. One second by default.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		timeout: defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.sweepInterval <= 0 {
+		o.sweepInterval = o.ttl / 2
+	}
+	return o
+}
@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+const scanCount = 1000
+
+// redisStore keeps keys under prefix in redis. Max size and sweep options are not applied,
+// keys are expired by redis and evicted by its maxmemory policy.
+// Cache interfaces have no errors, so failed commands are logged and treated as missing keys.
+type redisStore struct {
+	client  redis.UniversalClient
+	prefix  string
+	options *options
+}
+
+func newRedisStore(client redis.UniversalClient, prefix string, opts ...Option) *redisStore {
+	return &redisStore{
+		client:  client,
+		prefix:  prefix,
+		options: newOptions(opts...),
+	}
+}
+
+func (s *redisStore) key(key any) string {
+	return fmt.Sprint(s.prefix, ":", key)
+}
+
+func (s *redisStore) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.options.timeout)
+}
+
+func (s *redisStore) get(key any) ([]byte, bool) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	buf, err := s.client.Get(ctx, s.key(key)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Warnf("cache redis get command error: %v", err)
+		}
+		return nil, false
+	}
+	return buf, true
+}
+
+func (s *redisStore) put(key any, buf []byte, ttl time.Duration) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	if err := s.client.Set(ctx, s.key(key), buf, ttl).Err(); err != nil {
+		log.Warnf("cache redis set command error: %v", err)
+	}
+}
+
+// putNX puts value if key is missing and returns true if value was put.
+func (s *redisStore) putNX(key any, buf []byte, ttl time.Duration) bool {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	ok, err := s.client.SetNX(ctx, s.key(key), buf, ttl).Result()
+	if err != nil {
+		log.Warnf("cache redis setnx command error: %v", err)
+		return false
+	}
+	return ok
+}
+
+func (s *redisStore) exist(key any) bool {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	count, err := s.client.Exists(ctx, s.key(key)).Result()
+	if err != nil {
+		log.Warnf("cache redis exists command error: %v", err)
+		return false
+	}
+	return count > 0
+}
+
+func (s *redisStore) delete(key any) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	if err := s.client.Del(ctx, s.key(key)).Err(); err != nil {
+		log.Warnf("cache redis del command error: %v", err)
+	}
+}
+
+// scan iterates keys under prefix with scan command, so redis is not blocked as with keys command.
+func (s *redisStore) scan(callback func(ctx context.Context, keys []string) error) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	var cursor uint64
+
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, s.key("*"), scanCount).Result()
+		if err != nil {
+			return fmt.Errorf("cache redis scan command error: %v", err)
+		}
+		if len(keys) > 0 {
+			if err = callback(ctx, keys); err != nil {
+				return err
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// sweep removes nothing, since expired keys are removed by redis.
+func (s *redisStore) sweep() int {
+	return 0
+}
+
+func (s *redisStore) count() int {
+	var count int
+
+	if err := s.scan(func(_ context.Context, keys []string) error {
+		count += len(keys)
+		return nil
+	}); err != nil {
+		log.Warnf("cannot count cache redis keys: %v", err)
+	}
+	return count
+}
+
+func (s *redisStore) clear() {
+	if err := s.scan(func(ctx context.Context, keys []string) error {
+		if err := s.client.Unlink(ctx, keys...).Err(); err != nil {
+			return fmt.Errorf("cache redis unlink command error: %v", err)
+		}
+		return nil
+	}); err != nil {
+		log.Warnf("cannot clear cache redis keys: %v", err)
+	}
+}
+
+// NewRedisMemCache creates cache which values are encoded with codec and kept in redis under prefix.
+func NewRedisMemCache[K comparable, T any](client redis.UniversalClient, prefix string, codec Codec[T], opts ...Option) MemCache[K, T] {
+	return &redisMemCache[K, T]{
+		s:     newRedisStore(client, prefix, opts...),
+		codec: codec,
+	}
+}
+
+type redisMemCache[K comparable, T any] struct {
+	s     *redisStore
+	codec Codec[T]
+}
+
+func (c *redisMemCache[K, T]) Delete(key K) {
+	c.s.delete(key)
+}
+
+func (c *redisMemCache[K, T]) Exist(key K) bool {
+	return c.s.exist(key)
+}
+
+func (c *redisMemCache[K, T]) Count() int {
+	return c.s.count()
+}
+
+func (c *redisMemCache[K, T]) Sweep() int {
+	return c.s.sweep()
+}
+
+func (c *redisMemCache[K, T]) Get(key K) T {
+	value, _ := c.get(key)
+	return value
+}
+
+func (c *redisMemCache[K, T]) get(key K) (T, bool) {
+	var value T
+
+	buf, ok := c.s.get(key)
+	if !ok {
+		return value, false
+	}
+	value, err := c.codec.Decode(buf)
+	if err != nil {
+		log.Warnf("cannot decode cache value of key %v: %v", key, err)
+		return value, false
+	}
+	return value, true
+}
+
+func (c *redisMemCache[K, T]) Put(key K, value T) {
+	c.PutTTL(key, value, c.s.options.ttl)
+}
+
+func (c *redisMemCache[K, T]) PutTTL(key K, value T, ttl time.Duration) {
+	buf, err := c.codec.Encode(value)
+	if err != nil {
+		log.Warnf("cannot encode cache value of key %v: %v", key, err)
+		return
+	}
+	c.s.put(key, buf, ttl)
+}
+
+func (c *redisMemCache[K, T]) GetPut(key K, value T) T {
+	buf, err := c.codec.Encode(value)
+	if err != nil {
+		log.Warnf("cannot encode cache value of key %v: %v", key, err)
+		return value
+	}
+	if c.s.putNX(key, buf, c.s.options.ttl) {
+		return value
+	}
+	// key is put by other instance
+	if existing, ok := c.get(key); ok {
+		return existing
+	}
+	return value
+}
+
+// NewRedisKeyCache creates keys set kept in redis under prefix.
+func NewRedisKeyCache[T comparable](client redis.UniversalClient, prefix string, opts ...Option) KeyCache[T] {
+	return &redisKeyCache[T]{
+		s: newRedisStore(client, prefix, opts...),
+	}
+}
+
+type redisKeyCache[T comparable] struct {
+	s *redisStore
+}
+
+// redisKeyValue is stored for keys, since redis keys cannot be set without values.
+var redisKeyValue = []byte{1}
+
+func (c *redisKeyCache[T]) Exist(key T) bool {
+	return c.s.exist(key)
+}
+
+func (c *redisKeyCache[T]) Count() int {
+	return c.s.count()
+}
+
+func (c *redisKeyCache[T]) Put(key T) {
+	c.s.put(key, redisKeyValue, c.s.options.ttl)
+}
+
+func (c *redisKeyCache[T]) PutTTL(key T, ttl time.Duration) {
+	c.s.put(key, redisKeyValue, ttl)
+}
+
+func (c *redisKeyCache[T]) Delete(key T) {
+	c.s.delete(key)
+}
+
+func (c *redisKeyCache[T]) Clear() {
+	c.s.clear()
+}
+
+func (c *redisKeyCache[T]) Sweep() int {
+	return c.s.sweep()
+}
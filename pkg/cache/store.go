@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[K comparable, T any] struct {
+	key       K
+	value     T
+	expiresAt time.Time
+}
+
+func (e *entry[K, T]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// store keeps entries in lru order with optional expiry per key.
+type store[K comparable, T any] struct {
+	mtx       sync.Mutex
+	options   *options
+	items     map[K]*list.Element
+	order     *list.List
+	nextSweep time.Time
+}
+
+func newStore[K comparable, T any](opts ...Option) *store[K, T] {
+	s := &store[K, T]{
+		options: newOptions(opts...),
+		items:   map[K]*list.Element{},
+		order:   list.New(),
+	}
+	s.nextSweep = time.Now().Add(s.options.sweepInterval)
+
+	return s
+}
+
+func (s *store[K, T]) get(key K) (T, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.getLocked(key, time.Now())
+}
+
+func (s *store[K, T]) put(key K, value T, ttl time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.putLocked(key, value, ttl, time.Now())
+}
+
+// getPut returns existing value or puts passed value with default ttl.
+func (s *store[K, T]) getPut(key K, value T) T {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+
+	if existing, ok := s.getLocked(key, now); ok {
+		return existing
+	}
+	s.putLocked(key, value, s.options.ttl, now)
+
+	return value
+}
+
+func (s *store[K, T]) delete(key K) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.remove(elem)
+	}
+}
+
+func (s *store[K, T]) count() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.sweep(time.Now(), true)
+
+	return len(s.items)
+}
+
+func (s *store[K, T]) clear() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.items = map[K]*list.Element{}
+	s.order.Init()
+}
+
+func (s *store[K, T]) getLocked(key K, now time.Time) (T, bool) {
+	elem, ok := s.items[key]
+	if !ok {
+		return *new(T), false
+	}
+	e := elem.Value.(*entry[K, T])
+
+	if e.expired(now) {
+		s.remove(elem)
+		return *new(T), false
+	}
+	s.order.MoveToFront(elem)
+
+	return e.value, true
+}
+
+func (s *store[K, T]) putLocked(key K, value T, ttl time.Duration, now time.Time) {
+	s.sweep(now, false)
+
+	var expiresAt time.Time
+
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+	if elem, ok := s.items[key]; ok {
+		e := elem.Value.(*entry[K, T])
+		e.value = value
+		e.expiresAt = expiresAt
+
+		s.order.MoveToFront(elem)
+		return
+	}
+	s.items[key] = s.order.PushFront(&entry[K, T]{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+	// evict least recently used keys
+	for s.options.maxSize > 0 && len(s.items) > s.options.maxSize {
+		s.remove(s.order.Back())
+	}
+}
+
+// sweep removes expired keys once per sweep interval or immediately if forced and returns count of removed keys.
+func (s *store[K, T]) sweep(now time.Time, force bool) int {
+	if !force && (s.options.sweepInterval <= 0 || now.Before(s.nextSweep)) {
+		return 0
+	}
+	var removed int
+
+	for key, elem := range s.items {
+		if elem.Value.(*entry[K, T]).expired(now) {
+			s.order.Remove(elem)
+			delete(s.items, key)
+			removed++
+		}
+	}
+	s.nextSweep = now.Add(s.options.sweepInterval)
+
+	return removed
+}
+
+func (s *store[K, T]) sweepExpired() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.sweep(time.Now(), true)
+}
+
+func (s *store[K, T]) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.items, elem.Value.(*entry[K, T]).key)
+}
@@ -0,0 +1,180 @@
+package hashring
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+)
+
+const defaultReplicas = 128
+
+// Ring is consistent hash ring. Keys are moved only between changed nodes and their neighbours,
+// so most keys keep their node when nodes join or leave.
+type Ring interface {
+	// Set replaces ring nodes and returns true if nodes changed.
+	Set(nodes ...string) bool
+	Get(key string) (string, bool)
+	// Ranges returns hash ranges of keys owned by node, e.g. to select node keys by stored hashes.
+	Ranges(node string) []Range
+	Nodes() []string
+}
+
+// Range is inclusive range of keys hashes.
+type Range struct {
+	From uint64
+	To   uint64
+}
+
+type ring struct {
+	mtx      sync.RWMutex
+	replicas int
+	nodes    []string
+	hashes   []uint64
+	owners   map[uint64]string
+}
+
+// NewRing creates ring with replicas virtual nodes per node.
+func NewRing(replicas int) Ring {
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+	return &ring{
+		replicas: replicas,
+		owners:   map[uint64]string{},
+	}
+}
+
+func (r *ring) Set(nodes ...string) bool {
+	nodes = unique(nodes)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if equal(r.nodes, nodes) {
+		return false
+	}
+	hashes := make([]uint64, 0, len(nodes)*r.replicas)
+	owners := make(map[uint64]string, len(nodes)*r.replicas)
+
+	for _, node := range nodes {
+		for i := 0; i < r.replicas; i++ {
+			h := Hash(fmt.Sprintf("%s#%d", node, i))
+
+			// collided virtual node is kept by lesser node, so all instances build same ring
+			if owner, ok := owners[h]; ok && owner < node {
+				continue
+			}
+			if _, ok := owners[h]; !ok {
+				hashes = append(hashes, h)
+			}
+			owners[h] = node
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return hashes[i] < hashes[j]
+	})
+	r.nodes = nodes
+	r.hashes = hashes
+	r.owners = owners
+
+	return true
+}
+
+func (r *ring) Get(key string) (string, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if len(r.hashes) == 0 {
+		return "", false
+	}
+	h := Hash(key)
+
+	// first virtual node clockwise from key hash
+	i := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= h
+	})
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]], true
+}
+
+func (r *ring) Ranges(node string) []Range {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var ranges []Range
+
+	for i, h := range r.hashes {
+		if r.owners[h] != node {
+			continue
+		}
+		// virtual node owns keys after previous virtual node
+		var from uint64
+
+		if i > 0 {
+			from = r.hashes[i-1] + 1
+		}
+		ranges = appendRange(ranges, Range{From: from, To: h})
+	}
+	// first virtual node also owns keys after last one, since ring is wrapped
+	if len(r.hashes) > 0 && r.owners[r.hashes[0]] == node {
+		if last := r.hashes[len(r.hashes)-1]; last < math.MaxUint64 {
+			ranges = appendRange(ranges, Range{From: last + 1, To: math.MaxUint64})
+		}
+	}
+	return ranges
+}
+
+// appendRange merges range with previous adjacent range.
+func appendRange(ranges []Range, next Range) []Range {
+	if n := len(ranges); n > 0 && ranges[n-1].To+1 == next.From {
+		ranges[n-1].To = next.To
+		return ranges
+	}
+	return append(ranges, next)
+}
+
+func (r *ring) Nodes() []string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return append([]string(nil), r.nodes...)
+}
+
+// Hash returns key hash used to place keys on ring.
+func Hash(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func unique(nodes []string) []string {
+	set := make(map[string]struct{}, len(nodes))
+	result := make([]string, 0, len(nodes))
+
+	for _, node := range nodes {
+		if _, ok := set[node]; ok || node == "" {
+			continue
+		}
+		set[node] = struct{}{}
+		result = append(result, node)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestRingRangesCoverHashSpace(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []string
+	}{
+		{name: "single node", nodes: []string{"a"}},
+		{name: "two nodes", nodes: []string{"a", "b"}},
+		{name: "many nodes", nodes: []string{"a", "b", "c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRing(16)
+			r.Set(tt.nodes...)
+
+			var ranges []Range
+
+			for _, node := range tt.nodes {
+				ranges = append(ranges, r.Ranges(node)...)
+			}
+			sort.Slice(ranges, func(i, j int) bool {
+				return ranges[i].From < ranges[j].From
+			})
+			// ranges of all nodes are adjacent from zero to max hash without overlaps
+			var next uint64
+
+			for i, rng := range ranges {
+				if rng.From != next || rng.To < rng.From {
+					t.Fatalf("range %d %+v is not adjacent to previous one ending before %d", i, rng, next)
+				}
+				next = rng.To + 1
+			}
+			if last := ranges[len(ranges)-1]; last.To != math.MaxUint64 {
+				t.Fatalf("last range %+v does not end with max hash", last)
+			}
+		})
+	}
+}
+
+func TestRingRangesMatchGet(t *testing.T) {
+	r := NewRing(0)
+	r.Set("a", "b", "c")
+
+	ranges := map[string][]Range{}
+
+	for _, node := range r.Nodes() {
+		ranges[node] = r.Ranges(node)
+	}
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+
+		node, ok := r.Get(key)
+		if !ok {
+			t.Fatalf("Get(%s) found no node", key)
+		}
+		if !contains(ranges[node], Hash(key)) {
+			t.Fatalf("key %s of node %s is out of node ranges", key, node)
+		}
+	}
+}
+
+func TestRingWrapAround(t *testing.T) {
+	r := NewRing(1).(*ring)
+	r.Set("a", "b")
+
+	first, last := r.hashes[0], r.hashes[len(r.hashes)-1]
+	owner := r.owners[first]
+
+	// keys after last virtual node belong to first one
+	for _, h := range []uint64{last + 1, math.MaxUint64, 0, first} {
+		if !contains(r.Ranges(owner), h) {
+			t.Fatalf("hash %d is out of first virtual node owner %s ranges %+v", h, owner, r.Ranges(owner))
+		}
+	}
+	// single node owns whole hash space in one merged range
+	r.Set("a")
+
+	if got := r.Ranges("a"); len(got) != 1 || got[0] != (Range{From: 0, To: math.MaxUint64}) {
+		t.Fatalf("single node ranges = %+v, want whole hash space", got)
+	}
+}
+
+func TestRingSet(t *testing.T) {
+	r := NewRing(0)
+
+	if _, ok := r.Get("key"); ok {
+		t.Fatalf("Get of empty ring found node")
+	}
+	if got := r.Ranges("a"); len(got) != 0 {
+		t.Fatalf("Ranges of empty ring = %+v, want none", got)
+	}
+	if !r.Set("b", "a", "a", "") {
+		t.Fatalf("Set of new nodes reported not changed")
+	}
+	if r.Set("a", "b") {
+		t.Fatalf("Set of same nodes in other order reported changed")
+	}
+	if got := r.Nodes(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("Nodes = %v, want [a b]", got)
+	}
+	before := map[string]string{}
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		before[key], _ = r.Get(key)
+	}
+	r.Set("a", "b", "c")
+
+	// keys are moved only to joined node
+	for key, node := range before {
+		if got, _ := r.Get(key); got != node && got != "c" {
+			t.Fatalf("key %s moved from %s to %s, not to joined node", key, node, got)
+		}
+	}
+}
+
+func contains(ranges []Range, h uint64) bool {
+	for _, rng := range ranges {
+		if h >= rng.From && h <= rng.To {
+			return true
+		}
+	}
+	return false
+}
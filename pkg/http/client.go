@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"main/pkg/retries"
@@ -12,6 +13,8 @@ import (
 	"time"
 )
 
+var ErrNotFound = errors.New("not found")
+
 type Client struct {
 	ctx    context.Context
 	client *http.Client
@@ -31,22 +34,22 @@ type Query map[string][]string
 type Option func(*option)
 
 type option struct {
-	ctx     context.Context
-	headers Headers
-	query   Query
-	prefix  string
+	ctx        context.Context
+	headers    Headers
+	query      Query
+	prefix     string
+	retryCount int
+	retryWait  time.Duration
 }
 
 func (c *Client) Get(requestURL string, options ...Option) ([]byte, error) {
-	const (
-		retryCount = 10
-		retryWait  = 3 * time.Second
-	)
 	var (
 		buf []byte
 		err error
 	)
-	if err = retries.DoWithRetries(retryCount, retryWait, func() error {
+	o := newOptions(options...)
+
+	if err = retries.DoWithRetries(o.retryCount, o.retryWait, func() error {
 		buf, err = c.get(requestURL, options...)
 		return err
 
@@ -83,6 +86,9 @@ func (c *Client) get(requestURL string, options ...Option) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cannot read response body from %s: %v", requestURL, err)
 	}
+	if code := resp.StatusCode; code == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: got status code %s: %d", ErrNotFound, requestURL, code)
+	}
 	if code := resp.StatusCode; code != http.StatusOK {
 		err = fmt.Errorf("got wrong status code %s: %d. body: %s", requestURL, code, string(buf))
 
@@ -112,6 +118,14 @@ func WithQuery(query Query) Option {
 	}
 }
 
+// WithRetries overrides retries of failed request.
+func WithRetries(count int, wait time.Duration) Option {
+	return func(o *option) {
+		o.retryCount = count
+		o.retryWait = wait
+	}
+}
+
 func WithHeaders(headers Headers) Option {
 	return func(o *option) {
 		o.headers = headers
@@ -119,7 +133,10 @@ func WithHeaders(headers Headers) Option {
 }
 
 func newOptions(options ...Option) *option {
-	o := &option{}
+	o := &option{
+		retryCount: 10,
+		retryWait:  3 * time.Second,
+	}
 
 	for _, option := range options {
 		option(o)
@@ -153,15 +170,19 @@ func UnmarshalResponse(buf []byte, resp any) error {
 }
 
 func MustParseQuery(query string) url.Values {
-	parts := strings.Split(query, "?")
-	part := parts[len(parts)-1]
-	parsed, err := url.ParseQuery(part)
+	parsed, err := ParseQuery(query)
 	if err != nil {
 		panic(err)
 	}
 	return parsed
 }
 
+func ParseQuery(query string) (url.Values, error) {
+	parts := strings.Split(query, "?")
+	part := parts[len(parts)-1]
+	return url.ParseQuery(part)
+}
+
 func TrimQuery(s string) string {
 	parts := strings.Split(s, "?")
 	if len(parts) == 0 {
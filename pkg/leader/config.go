@@ -0,0 +1,39 @@
+package leader
+
+import "time"
+
+const (
+	defaultLockID        = 7_317_590_021
+	defaultCheckInterval = 2 * time.Second
+	defaultRetryInterval = 3 * time.Second
+)
+
+type Config struct {
+	// LockID is postgres advisory lock key shared by all instances
+	LockID int64 `yaml:"lock_id"`
+	// CheckInterval is leader connection ping period, leadership is lost on failed ping
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// RetryInterval is follower lock acquire period
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+func (c *Config) withDefault() *Config {
+	config := &Config{
+		LockID:        defaultLockID,
+		CheckInterval: defaultCheckInterval,
+		RetryInterval: defaultRetryInterval,
+	}
+	if c == nil {
+		return config
+	}
+	if c.LockID != 0 {
+		config.LockID = c.LockID
+	}
+	if c.CheckInterval > 0 {
+		config.CheckInterval = c.CheckInterval
+	}
+	if c.RetryInterval > 0 {
+		config.RetryInterval = c.RetryInterval
+	}
+	return config
+}
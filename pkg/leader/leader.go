@@ -0,0 +1,155 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"main/pkg/postgres"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	log "github.com/sirupsen/logrus"
+)
+
+// Elector elects single leader between instances with postgres session advisory lock.
+// Lock is held by dedicated connection, so it is released by postgres when leader connection drops.
+type Elector interface {
+	// Run campaigns for leadership until context is cancelled.
+	// Lead is called on becoming leader with context which is cancelled on leadership loss.
+	Run(ctx context.Context, lead func(ctx context.Context))
+	IsLeader() bool
+}
+
+type elector struct {
+	client postgres.Client
+	config *Config
+	leader atomic.Bool
+}
+
+func NewElector(client postgres.Client, config *Config) Elector {
+	return &elector{
+		client: client,
+		config: config.withDefault(),
+	}
+}
+
+func (e *elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+func (e *elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	for {
+		conn, err := e.tryLock(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Warnf("cannot try leader lock: %v", err)
+		}
+		if conn != nil {
+			e.hold(ctx, conn, lead)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.config.RetryInterval):
+		}
+	}
+}
+
+// tryLock returns connection holding advisory lock or nil if lock is held by other instance.
+func (e *elector) tryLock(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := e.client.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot acquire postgres connection: %v", err)
+	}
+	// detect dead leader connection on server side faster than with default keepalives
+	for _, query := range []string{
+		`SET tcp_keepalives_idle = 5`,
+		`SET tcp_keepalives_interval = 2`,
+		`SET tcp_keepalives_count = 3`,
+	} {
+		if _, err = conn.Exec(ctx, query); err != nil {
+			log.Warnf("cannot set leader connection keepalives: %s: %v", query, err)
+		}
+	}
+	var locked bool
+
+	if err = conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, e.config.LockID).Scan(&locked); err != nil {
+		release(conn)
+		return nil, fmt.Errorf("cannot do postgres query: %v", err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil
+	}
+	return conn, nil
+}
+
+// hold runs lead until leader connection fails or context is cancelled.
+func (e *elector) hold(ctx context.Context, conn *pgxpool.Conn, lead func(ctx context.Context)) {
+	// closed connection releases lock, so it never returns to pool with held lock
+	defer release(conn)
+
+	e.leader.Store(true)
+	defer e.leader.Store(false)
+
+	log.Infof("instance became leader")
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		lead(leadCtx)
+	}()
+	// stop lead before lock is released to other instance
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(e.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Infof("instance leadership stopped")
+			return
+		case <-ticker.C:
+			if err := e.ping(ctx, conn); err != nil {
+				log.Errorf("instance lost leadership: %v", err)
+				return
+			}
+		}
+	}
+}
+
+func (e *elector) ping(ctx context.Context, conn *pgxpool.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, e.config.CheckInterval)
+	defer cancel()
+
+	var locked bool
+
+	// lock may be lost with connection reset, so it is checked in pg_locks of current session
+	if err := conn.QueryRow(ctx, `SELECT EXISTS (
+            SELECT 1 FROM pg_locks
+            WHERE locktype = 'advisory' AND pid = pg_backend_pid() AND granted
+        )`).Scan(&locked); err != nil {
+		return fmt.Errorf("cannot ping leader connection: %v", err)
+	}
+	if !locked {
+		return fmt.Errorf("leader lock not held by connection")
+	}
+	return nil
+}
+
+func release(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := conn.Conn().Close(ctx); err != nil {
+		log.Warnf("cannot close leader connection: %v", err)
+	}
+	conn.Release()
+}
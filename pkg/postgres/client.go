@@ -20,6 +20,7 @@ type Client interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
 	BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
 }
 
 type (
@@ -2,18 +2,30 @@ package redis
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"main/pkg/utils"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/net/context"
 )
 
+// ErrKeyNotFound returned when key does not exist or expired.
+var ErrKeyNotFound = errors.New("redis key not found")
+
 type Cache interface {
 	Exists(ctx context.Context, keys ...any) (int, error)
 	Exist(ctx context.Context, key any) (bool, error)
 	Get(ctx context.Context, key any, val any) error
-	Set(ctx context.Context, key any, val any) error
+	// Set puts value with ttl, zero ttl puts value without expiration
+	Set(ctx context.Context, key any, val any, ttl time.Duration) error
+	// SetNX puts value with ttl if key does not exist and returns true if value was put
+	SetNX(ctx context.Context, key any, val any, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...any) error
+	Expire(ctx context.Context, key any, ttl time.Duration) error
+	// TTL returns remaining ttl of key, negative ttl returned for key without expiration
+	TTL(ctx context.Context, key any) (time.Duration, error)
 	Flush(ctx context.Context) error
 	HSet(ctx context.Context, key any, value any) error
 	HGet(ctx context.Context, key any, value any) error
@@ -25,6 +37,18 @@ type client struct {
 }
 
 func NewRedis(ctx context.Context, config *Config) (Cache, error) {
+	c, err := NewClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		ctx:    ctx,
+		client: c,
+	}, nil
+}
+
+// NewClient creates redis client checked with ping command.
+func NewClient(ctx context.Context, config *Config) (*redis.Client, error) {
 	c := redis.NewClient(&redis.Options{
 		Addr:     config.Addr,
 		Password: config.Password,
@@ -33,10 +57,7 @@ func NewRedis(ctx context.Context, config *Config) (Cache, error) {
 	if err := c.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("got error for redis ping command: %v", err)
 	}
-	return &client{
-		ctx:    ctx,
-		client: c,
-	}, nil
+	return c, nil
 }
 
 func (c *client) HGet(ctx context.Context, key any, value any) error {
@@ -69,13 +90,7 @@ func (c *client) Exist(ctx context.Context, key any) (bool, error) {
 }
 
 func (c *client) Exists(ctx context.Context, keys ...any) (int, error) {
-	str := make([]string, len(keys))
-
-	utils.ForEach(func(key any) {
-		str = append(str, redisKey(key))
-	}, keys...)
-
-	count, err := c.client.Exists(ctx, str...).Result()
+	count, err := c.client.Exists(ctx, redisKeys(keys...)...).Result()
 	if err != nil {
 		return 0, fmt.Errorf("redis exists command error: %v", err)
 	}
@@ -85,6 +100,9 @@ func (c *client) Exists(ctx context.Context, keys ...any) (int, error) {
 func (c *client) Get(ctx context.Context, key any, val any) error {
 	buf, err := c.client.Get(ctx, redisKey(key)).Bytes()
 	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrKeyNotFound
+		}
 		return fmt.Errorf("redis get command error: %v", err)
 	}
 	if err := json.Unmarshal(buf, val); err != nil {
@@ -93,17 +111,69 @@ func (c *client) Get(ctx context.Context, key any, val any) error {
 	return nil
 }
 
-func (c *client) Set(ctx context.Context, key any, val any) error {
+func (c *client) Set(ctx context.Context, key any, val any, ttl time.Duration) error {
 	buf, err := json.Marshal(val)
 	if err != nil {
 		return fmt.Errorf("json marshal error: %v", err)
 	}
-	if err := c.client.Set(ctx, fmt.Sprint(key), buf, 0).Err(); err != nil {
+	if err := c.client.Set(ctx, redisKey(key), buf, ttl).Err(); err != nil {
 		return fmt.Errorf("redis set key value error: %v", err)
 	}
 	return nil
 }
 
+func (c *client) SetNX(ctx context.Context, key any, val any, ttl time.Duration) (bool, error) {
+	buf, err := json.Marshal(val)
+	if err != nil {
+		return false, fmt.Errorf("json marshal error: %v", err)
+	}
+	ok, err := c.client.SetNX(ctx, redisKey(key), buf, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis setnx command error: %v", err)
+	}
+	return ok, nil
+}
+
+func (c *client) Delete(ctx context.Context, keys ...any) error {
+	if err := c.client.Del(ctx, redisKeys(keys...)...).Err(); err != nil {
+		return fmt.Errorf("redis del command error: %v", err)
+	}
+	return nil
+}
+
+func (c *client) Expire(ctx context.Context, key any, ttl time.Duration) error {
+	ok, err := c.client.PExpire(ctx, redisKey(key), ttl).Result()
+	if err != nil {
+		return fmt.Errorf("redis pexpire command error: %v", err)
+	}
+	if !ok {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+func (c *client) TTL(ctx context.Context, key any) (time.Duration, error) {
+	ttl, err := c.client.PTTL(ctx, redisKey(key)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis pttl command error: %v", err)
+	}
+	// redis returns -2 for missing key and -1 for key without expiration
+	if ttl == -2 {
+		return 0, ErrKeyNotFound
+	}
+	return ttl, nil
+}
+
 func redisKey(key any) string {
 	return fmt.Sprint(key)
 }
+
+func redisKeys(keys ...any) []string {
+	str := make([]string, 0, len(keys))
+
+	utils.ForEach(func(key any) {
+		str = append(str, redisKey(key))
+	}, keys...)
+
+	return str
+}
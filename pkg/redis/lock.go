@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrLockNotObtained returned when lock is held by other owner after all attempts.
+	ErrLockNotObtained = errors.New("redis lock not obtained")
+	// ErrLockNotHeld returned when lock expired or obtained by other owner.
+	ErrLockNotHeld = errors.New("redis lock not held")
+)
+
+// lock is released or extended only by owner which token is stored in key
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+type Locker interface {
+	// Obtain obtains lock for key with lease ttl. Lock expires if owner does not extend or release it.
+	Obtain(ctx context.Context, key string, ttl time.Duration, opts ...LockOption) (Lock, error)
+}
+
+type Lock interface {
+	Key() string
+	Token() string
+	// Extend prolongs lock lease with ttl if lock still held
+	Extend(ctx context.Context, ttl time.Duration) error
+	Release(ctx context.Context) error
+}
+
+type lockOptions struct {
+	retryCount int
+	retryWait  time.Duration
+}
+
+type LockOption func(o *lockOptions)
+
+// WithLockRetry sets attempts to obtain lock held by other owner. Lock is obtained with single attempt by default.
+func WithLockRetry(count int, wait time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.retryCount = count
+		o.retryWait = wait
+	}
+}
+
+type locker struct {
+	client redis.UniversalClient
+}
+
+func NewLocker(client redis.UniversalClient) Locker {
+	return &locker{
+		client: client,
+	}
+}
+
+func (l *locker) Obtain(ctx context.Context, key string, ttl time.Duration, opts ...LockOption) (Lock, error) {
+	o := &lockOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	token, err := newLockToken()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create lock token: %v", err)
+	}
+	for attempt := 0; ; attempt++ {
+		ok, err := l.client.SetNX(ctx, key, token, ttl).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis setnx command error: %v", err)
+		}
+		if ok {
+			return &lock{
+				client: l.client,
+				key:    key,
+				token:  token,
+			}, nil
+		}
+		if attempt >= o.retryCount {
+			return nil, ErrLockNotObtained
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(o.retryWait):
+		}
+	}
+}
+
+type lock struct {
+	client redis.UniversalClient
+	key    string
+	token  string
+}
+
+func (l *lock) Key() string {
+	return l.key
+}
+
+func (l *lock) Token() string {
+	return l.token
+}
+
+func (l *lock) Extend(ctx context.Context, ttl time.Duration) error {
+	res, err := extendScript.Run(ctx, l.client, []string{l.key}, l.token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("redis extend lock script error: %v", err)
+	}
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *lock) Release(ctx context.Context) error {
+	res, err := releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return fmt.Errorf("redis release lock script error: %v", err)
+	}
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func newLockToken() (string, error) {
+	buf := make([]byte, 16)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/net/context"
+)
+
+type Message struct {
+	Channel string
+	Payload []byte
+}
+
+// Decode unmarshals json message payload to value.
+func (m *Message) Decode(value any) error {
+	if err := json.Unmarshal(m.Payload, value); err != nil {
+		return fmt.Errorf("json unmarshal error: %v", err)
+	}
+	return nil
+}
+
+type PubSub interface {
+	// Publish sends value encoded to json to all channel subscribers
+	Publish(ctx context.Context, channel string, value any) error
+	// Subscribe calls handler for channels messages until context is cancelled
+	Subscribe(ctx context.Context, handler func(m *Message), channels ...string) error
+}
+
+type pubSub struct {
+	client redis.UniversalClient
+}
+
+func NewPubSub(client redis.UniversalClient) PubSub {
+	return &pubSub{
+		client: client,
+	}
+}
+
+func (p *pubSub) Publish(ctx context.Context, channel string, value any) error {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %v", err)
+	}
+	if err = p.client.Publish(ctx, channel, buf).Err(); err != nil {
+		return fmt.Errorf("redis publish command error: %v", err)
+	}
+	return nil
+}
+
+func (p *pubSub) Subscribe(ctx context.Context, handler func(m *Message), channels ...string) error {
+	sub := p.client.Subscribe(ctx, channels...)
+	defer sub.Close()
+
+	// wait subscription confirmation, so messages published after return are received
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("redis subscribe command error: %v", err)
+	}
+	// channel is reconnected by client on connection errors
+	messages := sub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			handler(&Message{
+				Channel: msg.Channel,
+				Payload: []byte(msg.Payload),
+			})
+		}
+	}
+}
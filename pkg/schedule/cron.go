@@ -0,0 +1,197 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec returns next run time after passed time.
+type Spec interface {
+	Next(t time.Time) time.Time
+}
+
+// ParseSpec parses cron expression with 5 fields (minute hour day-of-month month day-of-week),
+// descriptors @hourly, @daily, @weekly, @monthly and intervals "@every 10s" or "10s".
+func ParseSpec(spec string) (Spec, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	}
+	if interval := strings.TrimPrefix(spec, "@every "); interval != spec || !strings.Contains(spec, " ") {
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return nil, fmt.Errorf("wrong interval format: %s: %v", spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("interval must be positive: %s", spec)
+		}
+		return everySpec(d), nil
+	}
+	return parseCron(spec)
+}
+
+type everySpec time.Duration
+
+func (e everySpec) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+type cronSpec struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// day of month and day of week are matched with or if both restricted
+	daysStar     bool
+	weekdaysStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	// sunday may be set as 0 or 7
+	{name: "day of week", min: 0, max: 7},
+}
+
+func parseCron(spec string) (Spec, error) {
+	parts := strings.Fields(spec)
+
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields: %s", len(cronFields), spec)
+	}
+	bits := make([]uint64, len(cronFields))
+
+	for i, part := range parts {
+		var err error
+
+		if bits[i], err = parseCronField(part, cronFields[i]); err != nil {
+			return nil, fmt.Errorf("wrong cron %s field: %s: %v", cronFields[i].name, part, err)
+		}
+	}
+	// sunday set as 7 is matched by time weekday 0
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return &cronSpec{
+		minutes:      bits[0],
+		hours:        bits[1],
+		days:         bits[2],
+		months:       bits[3],
+		weekdays:     bits[4],
+		daysStar:     parts[2] == "*",
+		weekdaysStar: parts[4] == "*",
+	}, nil
+}
+
+// parseCronField parses comma separated list of values, ranges and steps, e.g. 1,5-10,*/15
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(field, ",") {
+		rng, step := item, 1
+
+		if index := strings.Index(item, "/"); index >= 0 {
+			var err error
+
+			if step, err = strconv.Atoi(item[index+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("wrong step: %s", item)
+			}
+			rng = item[:index]
+		}
+		from, to := f.min, f.max
+
+		switch {
+		case rng == "*":
+
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+
+			var err error
+
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("wrong range: %s", item)
+			}
+			if to, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("wrong range: %s", item)
+			}
+		default:
+			value, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("wrong value: %s", item)
+			}
+			from = value
+
+			// single value with step means range to max
+			if step == 1 {
+				to = value
+			}
+		}
+		if from < f.min || to > f.max || from > to {
+			return 0, fmt.Errorf("value out of range %d-%d: %s", f.min, f.max, item)
+		}
+		for value := from; value <= to; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func (c *cronSpec) Next(t time.Time) time.Time {
+	// cron expression without matching time in five years never fires, e.g. 30 february
+	const maxYears = 5
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxYears, 0, 0)
+
+	for t.Before(limit) {
+		if !has(c.months, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(c.hours, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(c.minutes, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	day, weekday := has(c.days, t.Day()), has(c.weekdays, int(t.Weekday()))
+
+	if c.daysStar || c.weekdaysStar {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+func has(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) != 0
+}
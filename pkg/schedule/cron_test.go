@@ -0,0 +1,174 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "every minute", spec: "* * * * *"},
+		{name: "list", spec: "0,15,30,45 * * * *"},
+		{name: "range", spec: "0 9-18 * * 1-5"},
+		{name: "step", spec: "*/15 * * * *"},
+		{name: "range with step", spec: "0 8-20/4 * * *"},
+		{name: "value with step", spec: "5/20 * * * *"},
+		{name: "sunday as 0", spec: "0 0 * * 0"},
+		{name: "sunday as 7", spec: "0 0 * * 7"},
+		{name: "range to sunday as 7", spec: "0 0 * * 5-7"},
+		{name: "descriptor", spec: "@daily"},
+		{name: "every interval", spec: "@every 10s"},
+		{name: "interval", spec: "1m"},
+		{name: "too few fields", spec: "* * * *", wantErr: true},
+		{name: "minute out of range", spec: "60 * * * *", wantErr: true},
+		{name: "day of month zero", spec: "0 0 0 * *", wantErr: true},
+		{name: "day of week out of range", spec: "0 0 * * 8", wantErr: true},
+		{name: "reversed range", spec: "0 10-5 * * *", wantErr: true},
+		{name: "zero step", spec: "*/0 * * * *", wantErr: true},
+		{name: "wrong value", spec: "a * * * *", wantErr: true},
+		{name: "negative interval", spec: "@every -1s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSpec(tt.spec)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronSpecNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "next minute",
+			spec: "* * * * *",
+			from: date(2023, 5, 10, 12, 30).Add(30 * time.Second),
+			want: date(2023, 5, 10, 12, 31),
+		},
+		{
+			name: "step",
+			spec: "*/15 * * * *",
+			from: date(2023, 5, 10, 12, 31),
+			want: date(2023, 5, 10, 12, 45),
+		},
+		{
+			name: "step across hour",
+			spec: "*/15 * * * *",
+			from: date(2023, 5, 10, 12, 45),
+			want: date(2023, 5, 10, 13, 0),
+		},
+		{
+			name: "list",
+			spec: "0 6,18 * * *",
+			from: date(2023, 5, 10, 7, 0),
+			want: date(2023, 5, 10, 18, 0),
+		},
+		{
+			name: "range of weekdays skips weekend",
+			spec: "0 9 * * 1-5",
+			// friday
+			from: date(2023, 5, 12, 10, 0),
+			want: date(2023, 5, 15, 9, 0),
+		},
+		{
+			name: "sunday as 7",
+			spec: "0 0 * * 7",
+			// wednesday
+			from: date(2023, 5, 10, 0, 0),
+			want: date(2023, 5, 14, 0, 0),
+		},
+		{
+			name: "sunday as 0",
+			spec: "0 0 * * 0",
+			from: date(2023, 5, 10, 0, 0),
+			want: date(2023, 5, 14, 0, 0),
+		},
+		{
+			name: "range to sunday as 7",
+			spec: "0 0 * * 6-7",
+			// saturday
+			from: date(2023, 5, 13, 0, 0),
+			want: date(2023, 5, 14, 0, 0),
+		},
+		{
+			name: "month rollover",
+			spec: "0 0 1 * *",
+			from: date(2023, 1, 31, 12, 0),
+			want: date(2023, 2, 1, 0, 0),
+		},
+		{
+			name: "short month skipped",
+			spec: "0 0 31 * *",
+			from: date(2023, 4, 1, 0, 0),
+			want: date(2023, 5, 31, 0, 0),
+		},
+		{
+			name: "year rollover",
+			spec: "30 23 * * *",
+			from: date(2023, 12, 31, 23, 30),
+			want: date(2024, 1, 1, 23, 30),
+		},
+		{
+			name: "month field across year",
+			spec: "0 0 1 1 *",
+			from: date(2023, 2, 1, 0, 0),
+			want: date(2024, 1, 1, 0, 0),
+		},
+		{
+			name: "leap day",
+			spec: "0 0 29 2 *",
+			from: date(2023, 3, 1, 0, 0),
+			want: date(2024, 2, 29, 0, 0),
+		},
+		{
+			name: "day of month or day of week",
+			spec: "0 0 15 * 1",
+			// wednesday, next monday is earlier than 15th
+			from: date(2023, 5, 3, 0, 0),
+			want: date(2023, 5, 8, 0, 0),
+		},
+		{
+			name: "never fires",
+			spec: "0 0 30 2 *",
+			from: date(2023, 1, 1, 0, 0),
+			want: time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseSpec(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSpec(%q) error = %v", tt.spec, err)
+			}
+			if got := spec.Next(tt.from); !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) of %q = %s, want %s", tt.from, tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEverySpecNext(t *testing.T) {
+	spec, err := ParseSpec("@every 90s")
+	if err != nil {
+		t.Fatalf("ParseSpec error = %v", err)
+	}
+	from := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	if got, want := spec.Next(from), from.Add(90*time.Second); !got.Equal(want) {
+		t.Fatalf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
@@ -1,32 +1,289 @@
 package schedule
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func DoWithSchedule(doInterval, errInterval string, immediately bool, f func() error) {
-	d := mustParseInterval(doInterval, "do")
-	e := mustParseInterval(errInterval, "err")
+type Mode int
 
-	for doIdx := 0; true; doIdx++ {
-		if doIdx == 0 && !immediately {
-			time.Sleep(d)
+const (
+	// FixedDelayMode schedules next run after previous run finished
+	FixedDelayMode Mode = 0
+	// FixedRateMode schedules runs by spec regardless of run duration, overlapping runs are skipped
+	FixedRateMode Mode = 1
+)
+
+const defaultMaxBackoff = 1 * time.Hour
+
+type Job struct {
+	Name string
+	// Spec is cron expression or interval, see ParseSpec
+	Spec        string
+	Mode        Mode
+	Immediately bool
+	// Jitter adds random delay up to jitter to every run
+	Jitter time.Duration
+	// ErrBackoff delays next run after error, doubled for every consecutive error up to MaxBackoff
+	ErrBackoff time.Duration
+	MaxBackoff time.Duration
+	Func       func(ctx context.Context) error
+}
+
+type JobStatus struct {
+	Name      string
+	Running   bool
+	LastRun   time.Time
+	NextRun   time.Time
+	LastError string
+	Runs      int64
+	Failures  int64
+	Skipped   int64
+}
+
+type Scheduler interface {
+	Add(job *Job) error
+	Run(ctx context.Context)
+	Status() []JobStatus
+}
+
+func NewScheduler() Scheduler {
+	return &scheduler{}
+}
+
+type scheduler struct {
+	mtx  sync.Mutex
+	jobs []*scheduledJob
+}
+
+type scheduledJob struct {
+	job  *Job
+	spec Spec
+
+	mtx    sync.Mutex
+	status JobStatus
+	// consecutive errors count for backoff
+	errors int
+}
+
+func (s *scheduler) Add(job *Job) error {
+	if job.Func == nil {
+		return fmt.Errorf("job %s func not specified", job.Name)
+	}
+	spec, err := ParseSpec(job.Spec)
+	if err != nil {
+		return fmt.Errorf("cannot parse job %s spec: %v", job.Name, err)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.jobs = append(s.jobs, &scheduledJob{
+		job:  job,
+		spec: spec,
+		status: JobStatus{
+			Name: job.Name,
+		},
+	})
+	return nil
+}
+
+// Run runs all added jobs until context is cancelled and waits for running jobs.
+func (s *scheduler) Run(ctx context.Context) {
+	s.mtx.Lock()
+	jobs := append([]*scheduledJob(nil), s.jobs...)
+	s.mtx.Unlock()
+
+	wg := sync.WaitGroup{}
+
+	for _, j := range jobs {
+		j := j
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			j.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *scheduler) Status() []JobStatus {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+
+	for _, j := range s.jobs {
+		j.mtx.Lock()
+		statuses = append(statuses, j.status)
+		j.mtx.Unlock()
+	}
+	return statuses
+}
+
+func (j *scheduledJob) loop(ctx context.Context) {
+	var (
+		wg   sync.WaitGroup
+		done = make(chan error, 1)
+		next = time.Now()
+		// fixed rate run is finished while next run is awaited
+		running bool
+	)
+	// wait running job before return
+	defer wg.Wait()
+
+	if !j.job.Immediately {
+		next = j.next(next)
+	}
+	for {
+		j.setNextRun(next)
+
+		select {
+		case <-ctx.Done():
+			if running {
+				// wait cancelled run, otherwise job stays running
+				j.finish(<-done)
+			}
+			return
+		case err := <-done:
+			running = false
+
+			// backoff of failed run delays next run if it is scheduled earlier
+			if backoff := j.finish(err); backoff > 0 && time.Now().Add(backoff).After(next) {
+				next = time.Now().Add(backoff)
+			}
+			continue
+		case <-time.After(time.Until(next) + j.jitter()):
 		}
-		if err := f(); err != nil {
-			log.Errorf("schedule func error: %v", err)
-			time.Sleep(e)
+		if !j.start() {
+			// previous run is not finished
+			next = j.next(time.Now())
 			continue
 		}
-		time.Sleep(d)
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			done <- j.run(ctx)
+		}()
+
+		if j.job.Mode == FixedRateMode {
+			running = true
+			next = j.next(next)
+
+			// skip missed runs
+			if now := time.Now(); next.Before(now) {
+				next = j.next(now)
+			}
+			continue
+		}
+		var err error
+
+		select {
+		case <-ctx.Done():
+			// wait cancelled run, otherwise job stays running
+			j.finish(<-done)
+			return
+		case err = <-done:
+		}
+		if backoff := j.finish(err); backoff > 0 {
+			next = time.Now().Add(backoff)
+			continue
+		}
+		next = j.next(time.Now())
 	}
 }
 
-func mustParseInterval(interval, typ string) time.Duration {
-	d, err := time.ParseDuration(interval)
-	if err != nil {
-		log.Fatalf("wrong %s interval format: %s", typ, interval)
+func (j *scheduledJob) run(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panic: %v", r)
+		}
+	}()
+	return j.job.Func(ctx)
+}
+
+// start marks job as running and reports false if previous run is not finished.
+func (j *scheduledJob) start() bool {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	if j.status.Running {
+		j.status.Skipped++
+		log.Warnf("schedule job %s skipped. previous run is not finished", j.job.Name)
+		return false
+	}
+	j.status.Running = true
+	j.status.LastRun = time.Now()
+	j.status.Runs++
+
+	return true
+}
+
+// finish marks job as finished and returns backoff if run failed.
+func (j *scheduledJob) finish(err error) time.Duration {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	j.status.Running = false
+
+	if err == nil || errors.Is(err, context.Canceled) {
+		j.errors = 0
+		j.status.LastError = ""
+		return 0
+	}
+	log.Errorf("schedule job %s error: %v", j.job.Name, err)
+
+	j.status.Failures++
+	j.status.LastError = err.Error()
+	j.errors++
+
+	return j.backoff()
+}
+
+func (j *scheduledJob) backoff() time.Duration {
+	if j.job.ErrBackoff <= 0 {
+		return 0
+	}
+	maxBackoff := j.job.MaxBackoff
+
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	backoff := j.job.ErrBackoff << (j.errors - 1)
+
+	if backoff <= 0 || backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+func (j *scheduledJob) next(t time.Time) time.Time {
+	next := j.spec.Next(t)
+
+	// spec never fires
+	if next.IsZero() {
+		return t.Add(defaultMaxBackoff)
+	}
+	return next
+}
+
+func (j *scheduledJob) setNextRun(next time.Time) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	j.status.NextRun = next
+}
+
+func (j *scheduledJob) jitter() time.Duration {
+	if j.job.Jitter <= 0 {
+		return 0
 	}
-	return d
+	return time.Duration(rand.Int63n(int64(j.job.Jitter)))
 }
@@ -0,0 +1,71 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerBackoff(t *testing.T) {
+	for _, mode := range []Mode{FixedDelayMode, FixedRateMode} {
+		var runs atomic.Int64
+
+		s := NewScheduler()
+
+		if err := s.Add(&Job{
+			Name:       "failing",
+			Spec:       "@every 10ms",
+			Mode:       mode,
+			ErrBackoff: 200 * time.Millisecond,
+			Func: func(ctx context.Context) error {
+				runs.Add(1)
+				return errors.New("failed")
+			},
+		}); err != nil {
+			t.Fatalf("Add error = %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		s.Run(ctx)
+		cancel()
+
+		// runs at 10ms and after 200ms backoff, next backoff is 400ms
+		if got := runs.Load(); got < 1 || got > 3 {
+			t.Fatalf("mode %d runs = %d, want 1-3 with backoff", mode, got)
+		}
+		status := s.Status()[0]
+
+		if status.Running || status.Failures != status.Runs {
+			t.Fatalf("mode %d status = %+v, want finished failed runs", mode, status)
+		}
+	}
+}
+
+func TestSchedulerFixedRateSkipsOverlapping(t *testing.T) {
+	var runs atomic.Int64
+
+	s := NewScheduler()
+
+	if err := s.Add(&Job{
+		Name: "slow",
+		Spec: "@every 10ms",
+		Mode: FixedRateMode,
+		Func: func(ctx context.Context) error {
+			runs.Add(1)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}); err != nil {
+		t.Fatalf("Add error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	s.Run(ctx)
+	cancel()
+
+	status := s.Status()[0]
+
+	if runs.Load() != 1 || status.Skipped == 0 || status.Running {
+		t.Fatalf("runs = %d, status = %+v, want single finished run and skipped runs", runs.Load(), status)
+	}
+}
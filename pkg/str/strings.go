@@ -19,6 +19,14 @@ func OneOf(f func(s string) bool, s ...string) bool {
 }
 
 func MustCast[T any](s string) T {
+	t, err := Cast[T](s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func Cast[T any](s string) (T, error) {
 	var (
 		iface any
 		err   error
@@ -34,9 +42,9 @@ func MustCast[T any](s string) T {
 		err = fmt.Errorf("unsupported type %v", typ)
 	}
 	if err != nil {
-		panic(err)
+		return *new(T), err
 	}
-	return iface.(T)
+	return iface.(T), nil
 }
 
 func BuildSentenceTags(s string) []string {
@@ -0,0 +1,83 @@
+package task
+
+import "time"
+
+type DropPolicy int
+
+const (
+	// BlockPolicy blocks Push until queue has free space
+	BlockPolicy DropPolicy = 0
+	// DropNewestPolicy rejects pushed task if queue is full
+	DropNewestPolicy DropPolicy = 1
+	// DropOldestPolicy drops the oldest queued task to free space for pushed task
+	DropOldestPolicy DropPolicy = 2
+)
+
+const (
+	defaultBuffer     = 100
+	defaultRetryWait  = 1 * time.Second
+	defaultMaxBackoff = 1 * time.Minute
+)
+
+type options struct {
+	buffer     int
+	policy     DropPolicy
+	timeout    time.Duration
+	retries    int
+	retryWait  time.Duration
+	maxBackoff time.Duration
+	drain      time.Duration
+}
+
+type Option func(o *options)
+
+// WithBuffer sets count of tasks which may wait for free worker.
+func WithBuffer(size int) Option {
+	return func(o *options) {
+		o.buffer = size
+	}
+}
+
+func WithDropPolicy(policy DropPolicy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithTimeout cancels task context after timeout. Each retry has own timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetries retries failed task count times with exponential backoff starting from wait.
+func WithRetries(count int, wait time.Duration) Option {
+	return func(o *options) {
+		o.retries = count
+		o.retryWait = wait
+	}
+}
+
+// WithDrainTimeout cancels context of running and queued tasks if queue is not drained in timeout after cancellation.
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.drain = timeout
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		buffer:     defaultBuffer,
+		policy:     BlockPolicy,
+		retryWait:  defaultRetryWait,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.buffer < 0 {
+		o.buffer = 0
+	}
+	return o
+}
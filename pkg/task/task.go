@@ -2,33 +2,112 @@ package task
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	ErrQueueFull   = errors.New("task queue is full")
+	ErrQueueClosed = errors.New("task queue is closed")
+)
+
+type Task func(ctx context.Context) error
+
 type Queue interface {
-	Push(task func() error)
+	Push(task Task) error
 	ContinuouslyHandle(ctx context.Context)
+	Stats() Stats
 }
 
-func NewQueue(workers int) Queue {
+type Stats struct {
+	Queued    int64
+	Running   int64
+	Succeeded int64
+	Failed    int64
+	Dropped   int64
+}
+
+func NewQueue(workers int, opts ...Option) Queue {
+	if workers <= 0 {
+		workers = 1
+	}
+	o := newOptions(opts...)
+
 	return &queue{
 		workers: workers,
-		tasks:   make(chan func() error),
+		options: o,
+		tasks:   make(chan Task, o.buffer),
 	}
 }
 
 type queue struct {
 	workers int
-	tasks   chan func() error
+	options *options
+	// closed guarded by mtx, pushes hold read lock while sending to tasks
+	mtx    sync.RWMutex
+	closed bool
+	tasks  chan Task
+
+	queued    atomic.Int64
+	running   atomic.Int64
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
 }
 
-func (q *queue) Push(task func() error) {
-	q.tasks <- task
+func (q *queue) Push(task Task) error {
+	q.mtx.RLock()
+	defer q.mtx.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	switch q.options.policy {
+	case DropNewestPolicy:
+		select {
+		case q.tasks <- task:
+		default:
+			q.dropped.Add(1)
+			return ErrQueueFull
+		}
+
+	case DropOldestPolicy:
+		for pushed := false; !pushed; {
+			select {
+			case q.tasks <- task:
+				pushed = true
+			default:
+				select {
+				case <-q.tasks:
+					q.queued.Add(-1)
+					q.dropped.Add(1)
+				default:
+				}
+			}
+		}
+
+	default:
+		q.tasks <- task
+	}
+	q.queued.Add(1)
+
+	return nil
 }
 
+// ContinuouslyHandle runs workers until context is cancelled.
+// After cancellation queue stops accepting tasks and blocks until queued tasks are handled
+// or drain timeout is exceeded, then tasks context is cancelled.
 func (q *queue) ContinuouslyHandle(ctx context.Context) {
+	// tasks context is not cancelled until queue drained
+	tasksCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	wg := sync.WaitGroup{}
 
 	for i := 0; i < q.workers; i++ {
@@ -37,19 +116,120 @@ func (q *queue) ContinuouslyHandle(ctx context.Context) {
 		go func() {
 			defer wg.Done()
 
+			for task := range q.tasks {
+				q.queued.Add(-1)
+				q.handle(tasksCtx, task)
+			}
+		}()
+	}
+	<-ctx.Done()
+
+	q.mtx.Lock()
+	q.closed = true
+	close(q.tasks)
+	q.mtx.Unlock()
+
+	log.Infof("task handling stopped. context cancelled. draining %d queued tasks", q.queued.Load())
+
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	if q.options.drain <= 0 {
+		<-done
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(q.options.drain):
+		log.Warnf("task queue drain timeout exceeded. cancelling %d running and %d queued tasks",
+			q.running.Load(), q.queued.Load())
+		cancel()
+		<-done
+	}
+}
+
+func (q *queue) Stats() Stats {
+	return Stats{
+		Queued:    q.queued.Load(),
+		Running:   q.running.Load(),
+		Succeeded: q.succeeded.Load(),
+		Failed:    q.failed.Load(),
+		Dropped:   q.dropped.Load(),
+	}
+}
+
+func (q *queue) handle(ctx context.Context, task Task) {
+	q.running.Add(1)
+	defer q.running.Add(-1)
+
+	var err error
+
+tries:
+	for try := 0; try <= q.options.retries; try++ {
+		if try > 0 {
+			log.Warnf("task try: %d. error: %v", try, err)
+
+			// backoff is interrupted by drain timeout, so worker does not block shutdown
 			select {
 			case <-ctx.Done():
-				log.Infof("task handling stopped. context cancelled")
-				return
-
-			case task, ok := <-q.tasks:
-				if ok {
-					if err := task(); err != nil {
-						log.Errorf("task handling error: %v", err)
-					}
-				}
+				break tries
+			case <-time.After(q.backoff(try)):
 			}
-		}()
+		}
+		if err = q.run(ctx, task); err == nil {
+			q.succeeded.Add(1)
+			return
+		}
+		// cancelled by drain timeout
+		if ctx.Err() != nil {
+			break
+		}
+		var panicErr *panicError
+
+		// panics are not retried
+		if errors.As(err, &panicErr) {
+			break
+		}
 	}
-	wg.Wait()
+	q.failed.Add(1)
+	log.Errorf("task handling error: %v", err)
+}
+
+func (q *queue) run(ctx context.Context, task Task) (err error) {
+	if q.options.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, q.options.timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{
+				value: r,
+				stack: debug.Stack(),
+			}
+		}
+	}()
+	return task(ctx)
+}
+
+func (q *queue) backoff(try int) time.Duration {
+	wait := q.options.retryWait << (try - 1)
+
+	if wait <= 0 || wait > q.options.maxBackoff {
+		return q.options.maxBackoff
+	}
+	return wait
+}
+
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("task panic: %v\n%s", e.value, e.stack)
 }
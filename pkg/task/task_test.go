@@ -0,0 +1,45 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrainTimeoutInterruptsRetryBackoff(t *testing.T) {
+	q := NewQueue(1,
+		WithRetries(3, time.Minute),
+		WithDrainTimeout(50*time.Millisecond),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		q.ContinuouslyHandle(ctx)
+	}()
+	tried := make(chan struct{}, 1)
+
+	if err := q.Push(func(ctx context.Context) error {
+		select {
+		case tried <- struct{}{}:
+		default:
+		}
+		return errors.New("failed")
+	}); err != nil {
+		t.Fatalf("Push error = %v", err)
+	}
+	// worker waits retry backoff after first try
+	<-tried
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("queue is not stopped after drain timeout while task waits retry backoff")
+	}
+	if stats := q.Stats(); stats.Failed != 1 || stats.Running != 0 {
+		t.Fatalf("stats = %+v, want single failed task", stats)
+	}
+}
@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"fmt"
+	"sync"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+type callbackOption struct {
+	text      string
+	showAlert bool
+}
+
+type CallbackOption func(o *callbackOption)
+
+// WithToast shows short notification at the top of the chat screen.
+func WithToast(text string) CallbackOption {
+	return func(o *callbackOption) {
+		o.text = text
+		o.showAlert = false
+	}
+}
+
+// WithAlert shows alert dialog which user must close.
+func WithAlert(text string) CallbackOption {
+	return func(o *callbackOption) {
+		o.text = text
+		o.showAlert = true
+	}
+}
+
+// callbacks tracks answered callback queries, every query must be answered exactly once.
+type callbacks struct {
+	mtx      sync.Mutex
+	answered map[string]struct{}
+}
+
+func newCallbacks() *callbacks {
+	return &callbacks{
+		answered: map[string]struct{}{},
+	}
+}
+
+// answer marks callback query as answered and reports whether it was not answered before.
+func (c *callbacks) answer(callbackID string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if _, ok := c.answered[callbackID]; ok {
+		return false
+	}
+	c.answered[callbackID] = struct{}{}
+	return true
+}
+
+func (c *callbacks) forget(callbackID string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.answered, callbackID)
+}
+
+func (b *bot) AnswerCallback(callbackID string, options ...CallbackOption) error {
+	if callbackID == "" || !b.callbacks.answer(callbackID) {
+		return nil
+	}
+	o := &callbackOption{}
+
+	for _, option := range options {
+		option(o)
+	}
+	// callback query answers are not limited and expire fast, so sent without scheduler and retries
+	if _, err := b.api.Request(tg.CallbackConfig{
+		CallbackQueryID: callbackID,
+		Text:            o.text,
+		ShowAlert:       o.showAlert,
+	}); err != nil {
+		return fmt.Errorf("cannot answer telegram callback query: %v", err)
+	}
+	return nil
+}
+
+// ensureCallbackAnswered answers callback query without text if handler did not answer it.
+func (b *bot) ensureCallbackAnswered(m *Message) {
+	if !m.FromCallback() {
+		return
+	}
+	if err := b.AnswerCallback(m.CallbackID); err != nil {
+		log.Warnf("cannot answer callback query: %v", err)
+	}
+	b.callbacks.forget(m.CallbackID)
+}
@@ -0,0 +1,254 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"main/pkg/cache"
+	"strconv"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// telegram limits callback_data to 64 bytes
+	callbackDataLimit = 64
+
+	callbackTokenPrefix = "~"
+	callbackTokenSize   = 12
+	defaultCallbackTTL  = 48 * time.Hour
+
+	callbackSignSeparator   = "#"
+	callbackExpirySeparator = "."
+	callbackSignSize        = 8
+)
+
+var (
+	ErrCallbackExpired = errors.New("callback data expired")
+	ErrCallbackInvalid = errors.New("callback data invalid")
+)
+
+type CallbackConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+	// Secret signs callback data, so it must be same for all instances and kept between restarts
+	Secret string `yaml:"secret"`
+	// PreviousSecrets are only verified, so buttons signed before secret rotation keep working.
+	// Rotated secret is moved here and removed after buttons lifetime, e.g. after expiry
+	PreviousSecrets []string      `yaml:"previous_secrets"`
+	Expiry          time.Duration `yaml:"expiry"`
+}
+
+// CallbackCodec converts callback payloads to callback data bound to chat which fits telegram limit and back.
+type CallbackCodec interface {
+	Encode(chatID int64, payload string) (string, error)
+	Decode(chatID int64, data string) (string, error)
+}
+
+type callbackToken struct {
+	ChatID  int64  `json:"chat_id"`
+	Payload string `json:"payload"`
+}
+
+// callbackCodec signs payloads with chat id and optional expiry.
+// Signed payloads are kept inline if fit telegram limit, otherwise stored under short tokens in caches backend,
+// so tokens are decoded by any instance and after restart if caches are shared.
+type callbackCodec struct {
+	ttl      time.Duration
+	expiry   time.Duration
+	secret   []byte
+	verified [][]byte
+	tokens   cache.MemCache[string, *callbackToken]
+	payloads cache.MemCache[string, string]
+}
+
+func NewCallbackCodec(config *CallbackConfig, caches *cache.Backend) (CallbackCodec, error) {
+	if config == nil || config.Secret == "" {
+		return nil, fmt.Errorf("callback secret not specified in telegram.callback.secret")
+	}
+	c := &callbackCodec{
+		ttl:    defaultCallbackTTL,
+		expiry: config.Expiry,
+		secret: []byte(config.Secret),
+	}
+	if config.TTL > 0 {
+		c.ttl = config.TTL
+	}
+	// current secret is verified first
+	c.verified = append(c.verified, c.secret)
+
+	for _, secret := range config.PreviousSecrets {
+		if secret != "" {
+			c.verified = append(c.verified, []byte(secret))
+		}
+	}
+	c.tokens = cache.NewBackendMemCache[string, *callbackToken](caches, "telegram:callback:tokens",
+		cache.WithTTL(c.ttl),
+		cache.WithSweepInterval(c.ttl/2),
+	)
+	c.payloads = cache.NewBackendMemCache[string, string](caches, "telegram:callback:payloads",
+		cache.WithTTL(c.ttl),
+		cache.WithSweepInterval(c.ttl/2),
+	)
+	return c, nil
+}
+
+func (c *callbackCodec) Encode(chatID int64, payload string) (string, error) {
+	if data := c.sign(chatID, payload, time.Now()); len(data) <= callbackDataLimit {
+		return data, nil
+	}
+	key := callbackPayloadKey(chatID, payload)
+
+	token := &callbackToken{
+		ChatID:  chatID,
+		Payload: payload,
+	}
+	// reuse token for same payload and prolong it
+	if data := c.payloads.Get(key); data != "" && c.tokens.Exist(data) {
+		c.tokens.Put(data, token)
+		c.payloads.Put(key, data)
+
+		return data, nil
+	}
+	data, err := newCallbackToken()
+	if err != nil {
+		return "", fmt.Errorf("cannot create callback token: %v", err)
+	}
+	c.tokens.Put(data, token)
+	c.payloads.Put(key, data)
+
+	return data, nil
+}
+
+func (c *callbackCodec) Decode(chatID int64, data string) (string, error) {
+	if strings.HasPrefix(data, callbackTokenPrefix) {
+		return c.decodeToken(chatID, data)
+	}
+	return c.verify(chatID, data, time.Now())
+}
+
+func (c *callbackCodec) decodeToken(chatID int64, data string) (string, error) {
+	if len(data) != len(callbackTokenPrefix)+base64.RawURLEncoding.EncodedLen(callbackTokenSize) {
+		return "", fmt.Errorf("%w: wrong token length", ErrCallbackInvalid)
+	}
+	token := c.tokens.Get(data)
+	if token == nil {
+		return "", ErrCallbackExpired
+	}
+	if token.ChatID != chatID {
+		return "", fmt.Errorf("%w: token issued for another chat", ErrCallbackInvalid)
+	}
+	return token.Payload, nil
+}
+
+// sign appends signature of chat id, payload and optional expiry: payload#[expiry.]signature
+func (c *callbackCodec) sign(chatID int64, payload string, now time.Time) string {
+	var expiry string
+
+	if c.expiry > 0 {
+		expiry = strconv.FormatInt(now.Add(c.expiry).Unix(), 36)
+	}
+	sign := signature(c.secret, chatID, payload, expiry)
+
+	if expiry != "" {
+		return fmt.Sprint(payload, callbackSignSeparator, expiry, callbackExpirySeparator, sign)
+	}
+	return fmt.Sprint(payload, callbackSignSeparator, sign)
+}
+
+func (c *callbackCodec) verify(chatID int64, data string, now time.Time) (string, error) {
+	index := strings.LastIndex(data, callbackSignSeparator)
+	if index < 0 {
+		return "", fmt.Errorf("%w: signature not found", ErrCallbackInvalid)
+	}
+	payload, signed := data[:index], data[index+len(callbackSignSeparator):]
+
+	var expiry, sign string
+
+	if parts := strings.SplitN(signed, callbackExpirySeparator, 2); len(parts) == 2 {
+		expiry, sign = parts[0], parts[1]
+	} else {
+		sign = parts[0]
+	}
+	if !c.verifySignature(sign, chatID, payload, expiry) {
+		return "", fmt.Errorf("%w: wrong signature", ErrCallbackInvalid)
+	}
+	if expiry != "" {
+		expiresAt, err := strconv.ParseInt(expiry, 36, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: wrong expiry: %v", ErrCallbackInvalid, err)
+		}
+		if now.Unix() > expiresAt {
+			return "", ErrCallbackExpired
+		}
+	}
+	return payload, nil
+}
+
+// verifySignature checks signature by current and previous secrets.
+func (c *callbackCodec) verifySignature(sign string, chatID int64, payload, expiry string) bool {
+	for _, secret := range c.verified {
+		if hmac.Equal([]byte(sign), []byte(signature(secret, chatID, payload, expiry))) {
+			return true
+		}
+	}
+	return false
+}
+
+func signature(secret []byte, chatID int64, payload, expiry string) string {
+	mac := hmac.New(sha256.New, secret)
+
+	mac.Write([]byte(strconv.FormatInt(chatID, 10)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expiry))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignSize])
+}
+
+// callbackPayloadKey returns short key of chat payload, since payloads exceed telegram limit.
+func callbackPayloadKey(chatID int64, payload string) string {
+	sum := sha256.Sum256([]byte(payload))
+	return fmt.Sprint(chatID, ":", base64.RawURLEncoding.EncodeToString(sum[:]))
+}
+
+func newCallbackToken() (string, error) {
+	buf := make([]byte, callbackTokenSize)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(callbackTokenPrefix, base64.RawURLEncoding.EncodeToString(buf)), nil
+}
+
+// encodeKeyboard returns copy of keyboard markup with encoded callback data for chat.
+func encodeKeyboard(codec CallbackCodec, chatID int64, k *InlineKeyboard) (*tg.InlineKeyboardMarkup, error) {
+	if k == nil {
+		return nil, nil
+	}
+	rows := make([][]tg.InlineKeyboardButton, 0, len(k.markup.InlineKeyboard))
+
+	for _, row := range k.markup.InlineKeyboard {
+		encoded := make([]tg.InlineKeyboardButton, 0, len(row))
+
+		for _, button := range row {
+			if button.CallbackData != nil {
+				data, err := codec.Encode(chatID, *button.CallbackData)
+				if err != nil {
+					return nil, fmt.Errorf("cannot encode callback data: %v", err)
+				}
+				button.CallbackData = &data
+			}
+			encoded = append(encoded, button)
+		}
+		rows = append(rows, encoded)
+	}
+	markup := tg.NewInlineKeyboardMarkup(rows...)
+
+	return &markup, nil
+}
@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"errors"
+	"main/pkg/cache"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testChatID = 42
+
+func newTestCodec(t *testing.T, config *CallbackConfig) *callbackCodec {
+	t.Helper()
+
+	c, err := NewCallbackCodec(config, &cache.Backend{})
+	if err != nil {
+		t.Fatalf("NewCallbackCodec error = %v", err)
+	}
+	return c.(*callbackCodec)
+}
+
+func TestNewCallbackCodecRequiresSecret(t *testing.T) {
+	for _, config := range []*CallbackConfig{nil, {}} {
+		if _, err := NewCallbackCodec(config, &cache.Backend{}); err == nil {
+			t.Fatalf("NewCallbackCodec(%+v) created codec without secret", config)
+		}
+	}
+}
+
+func TestCallbackCodecSignAndVerify(t *testing.T) {
+	c := newTestCodec(t, &CallbackConfig{Secret: "secret"})
+
+	data, err := c.Encode(testChatID, "like:123")
+	if err != nil {
+		t.Fatalf("Encode error = %v", err)
+	}
+	if !strings.HasPrefix(data, "like:123"+callbackSignSeparator) || len(data) > callbackDataLimit {
+		t.Fatalf("Encode = %q, want signed inline payload within limit", data)
+	}
+	if payload, err := c.Decode(testChatID, data); err != nil || payload != "like:123" {
+		t.Fatalf("Decode = %q, %v, want like:123", payload, err)
+	}
+	tests := []struct {
+		name   string
+		chatID int64
+		data   string
+	}{
+		{name: "another chat", chatID: testChatID + 1, data: data},
+		{name: "changed payload", chatID: testChatID, data: strings.Replace(data, "123", "124", 1)},
+		{name: "changed signature", chatID: testChatID, data: data[:len(data)-1] + "A"},
+		{name: "not signed", chatID: testChatID, data: "like:123"},
+		{name: "signed by another secret", chatID: testChatID, data: newTestCodec(t, &CallbackConfig{Secret: "other"}).sign(testChatID, "like:123", time.Now())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Decode(tt.chatID, tt.data); !errors.Is(err, ErrCallbackInvalid) {
+				t.Fatalf("Decode(%d, %q) error = %v, want %v", tt.chatID, tt.data, err, ErrCallbackInvalid)
+			}
+		})
+	}
+}
+
+func TestCallbackCodecSecretRotation(t *testing.T) {
+	old := newTestCodec(t, &CallbackConfig{Secret: "old"})
+	data := old.sign(testChatID, "fav:1", time.Now())
+
+	rotated := newTestCodec(t, &CallbackConfig{Secret: "new", PreviousSecrets: []string{"old", ""}})
+
+	if payload, err := rotated.Decode(testChatID, data); err != nil || payload != "fav:1" {
+		t.Fatalf("Decode of data signed by previous secret = %q, %v, want fav:1", payload, err)
+	}
+	// new data is signed by current secret only
+	if data = rotated.sign(testChatID, "fav:1", time.Now()); old.verifySignature(
+		data[strings.LastIndex(data, callbackSignSeparator)+1:], testChatID, "fav:1", "") {
+		t.Fatalf("data is signed by previous secret after rotation")
+	}
+	// previous secret removed after buttons lifetime
+	removed := newTestCodec(t, &CallbackConfig{Secret: "new"})
+
+	if _, err := removed.Decode(testChatID, old.sign(testChatID, "fav:1", time.Now())); !errors.Is(err, ErrCallbackInvalid) {
+		t.Fatalf("Decode of data signed by removed secret error = %v, want %v", err, ErrCallbackInvalid)
+	}
+}
+
+func TestCallbackCodecExpiry(t *testing.T) {
+	c := newTestCodec(t, &CallbackConfig{Secret: "secret", Expiry: time.Hour})
+	now := time.Now()
+
+	data := c.sign(testChatID, "hide:7", now)
+
+	if !strings.Contains(data, callbackExpirySeparator) {
+		t.Fatalf("sign = %q, want expiry", data)
+	}
+	if payload, err := c.verify(testChatID, data, now.Add(30*time.Minute)); err != nil || payload != "hide:7" {
+		t.Fatalf("verify before expiry = %q, %v, want hide:7", payload, err)
+	}
+	if _, err := c.verify(testChatID, data, now.Add(2*time.Hour)); !errors.Is(err, ErrCallbackExpired) {
+		t.Fatalf("verify after expiry error = %v, want %v", err, ErrCallbackExpired)
+	}
+	// expiry is signed, so it cannot be prolonged
+	index := strings.LastIndex(data, callbackSignSeparator)
+	prolonged := data[:index+1] + "zzzzzzz" + data[strings.LastIndex(data, callbackExpirySeparator):]
+
+	if _, err := c.verify(testChatID, prolonged, now.Add(2*time.Hour)); !errors.Is(err, ErrCallbackInvalid) {
+		t.Fatalf("verify of prolonged expiry error = %v, want %v", err, ErrCallbackInvalid)
+	}
+}
+
+func TestCallbackCodecTokens(t *testing.T) {
+	c := newTestCodec(t, &CallbackConfig{Secret: "secret"})
+	payload := "tune:" + strings.Repeat("x", callbackDataLimit)
+
+	data, err := c.Encode(testChatID, payload)
+	if err != nil {
+		t.Fatalf("Encode error = %v", err)
+	}
+	if !strings.HasPrefix(data, callbackTokenPrefix) || len(data) > callbackDataLimit {
+		t.Fatalf("Encode = %q, want token within limit", data)
+	}
+	if got, err := c.Decode(testChatID, data); err != nil || got != payload {
+		t.Fatalf("Decode of token = %q, %v, want payload", got, err)
+	}
+	// same payload reuses token
+	if again, err := c.Encode(testChatID, payload); err != nil || again != data {
+		t.Fatalf("Encode of same payload = %q, %v, want %q", again, err, data)
+	}
+	if other, err := c.Encode(testChatID+1, payload); err != nil || other == data {
+		t.Fatalf("Encode of same payload for another chat = %q, %v, want new token", other, err)
+	}
+	if _, err = c.Decode(testChatID+1, data); !errors.Is(err, ErrCallbackInvalid) {
+		t.Fatalf("Decode of token in another chat error = %v, want %v", err, ErrCallbackInvalid)
+	}
+	unknown, err := newCallbackToken()
+	if err != nil {
+		t.Fatalf("newCallbackToken error = %v", err)
+	}
+	if _, err = c.Decode(testChatID, unknown); !errors.Is(err, ErrCallbackExpired) {
+		t.Fatalf("Decode of unknown token error = %v, want %v", err, ErrCallbackExpired)
+	}
+	if _, err = c.Decode(testChatID, callbackTokenPrefix+"short"); !errors.Is(err, ErrCallbackInvalid) {
+		t.Fatalf("Decode of short token error = %v, want %v", err, ErrCallbackInvalid)
+	}
+}
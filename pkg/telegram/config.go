@@ -0,0 +1,23 @@
+package telegram
+
+const (
+	PollingMode = "polling"
+	WebhookMode = "webhook"
+)
+
+type Config struct {
+	Token     string           `yaml:"token" required:"true"`
+	Mode      string           `yaml:"mode"`
+	Webhook   *WebhookConfig   `yaml:"webhook"`
+	Scheduler *SchedulerConfig `yaml:"scheduler"`
+	Callback  *CallbackConfig  `yaml:"callback"`
+	Workers   *WorkersConfig   `yaml:"workers"`
+}
+
+type WebhookConfig struct {
+	Listen   string `yaml:"listen" required:"true"`
+	URL      string `yaml:"url" required:"true"`
+	Secret   string `yaml:"secret"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
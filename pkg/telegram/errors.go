@@ -0,0 +1,84 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var (
+	ErrBotBlocked      = errors.New("bot was blocked by the user")
+	ErrUserDeactivated = errors.New("user is deactivated")
+	ErrBotKicked       = errors.New("bot was kicked from the chat")
+	ErrChatNotFound    = errors.New("chat not found")
+	// ErrMessageNotModified returned when message is edited with same content, so edit is treated as done
+	ErrMessageNotModified = errors.New("message is not modified")
+)
+
+// ChatMigratedError returned when group chat was upgraded to supergroup with new chat id.
+type ChatMigratedError struct {
+	ChatID          int64
+	MigrateToChatID int64
+}
+
+func (e *ChatMigratedError) Error() string {
+	return fmt.Sprintf("chat %d migrated to supergroup chat %d", e.ChatID, e.MigrateToChatID)
+}
+
+// IsChatUnavailable reports whether bot cannot send messages to chat anymore.
+func IsChatUnavailable(err error) bool {
+	return errors.Is(err, ErrBotBlocked) ||
+		errors.Is(err, ErrUserDeactivated) ||
+		errors.Is(err, ErrBotKicked) ||
+		errors.Is(err, ErrChatNotFound)
+}
+
+// AsChatMigrated returns migration details if chat was upgraded to supergroup.
+func AsChatMigrated(err error) (*ChatMigratedError, bool) {
+	var migrated *ChatMigratedError
+
+	if errors.As(err, &migrated) {
+		return migrated, true
+	}
+	return nil, false
+}
+
+// classifyError converts telegram api error to package error if it is permanent for chat.
+// Returns nil for errors which may be retried.
+func classifyError(chatID int64, err error) error {
+	var apiErr *tg.Error
+
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+	if apiErr.MigrateToChatID != 0 {
+		return &ChatMigratedError{
+			ChatID:          chatID,
+			MigrateToChatID: apiErr.MigrateToChatID,
+		}
+	}
+	message := strings.ToLower(apiErr.Message)
+
+	switch apiErr.Code {
+	case http.StatusForbidden:
+		switch {
+		case strings.Contains(message, "blocked"):
+			return ErrBotBlocked
+		case strings.Contains(message, "deactivated"):
+			return ErrUserDeactivated
+		case strings.Contains(message, "kicked"), strings.Contains(message, "not a member"):
+			return ErrBotKicked
+		}
+	case http.StatusBadRequest:
+		switch {
+		case strings.Contains(message, "chat not found"):
+			return ErrChatNotFound
+		case strings.Contains(message, "message is not modified"):
+			return ErrMessageNotModified
+		}
+	}
+	return nil
+}
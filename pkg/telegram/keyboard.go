@@ -6,9 +6,22 @@ type InlineKeyboard struct {
 	markup tg.InlineKeyboardMarkup
 }
 
+// InlineKeyboardButton is a callback button by default.
+// Button kind is chosen by first set field: LoginURL, URL, SwitchInlineQuery, SwitchInlineQueryCurrentChat.
 type InlineKeyboardButton struct {
-	Text    string
-	Command string
+	Text                         string
+	Command                      string
+	URL                          string
+	SwitchInlineQuery            *string
+	SwitchInlineQueryCurrentChat *string
+	LoginURL                     *LoginURL
+}
+
+type LoginURL struct {
+	URL                string
+	ForwardText        string
+	BotUsername        string
+	RequestWriteAccess bool
 }
 
 type InlineKeyboardButtonsMarkup int
@@ -18,27 +31,118 @@ const (
 	InColButtonsMarkup InlineKeyboardButtonsMarkup = 1
 )
 
+func NewURLButton(text, url string) InlineKeyboardButton {
+	return InlineKeyboardButton{
+		Text: text,
+		URL:  url,
+	}
+}
+
+func NewSwitchInlineQueryButton(text, query string, currentChat bool) InlineKeyboardButton {
+	if currentChat {
+		return InlineKeyboardButton{
+			Text:                         text,
+			SwitchInlineQueryCurrentChat: &query,
+		}
+	}
+	return InlineKeyboardButton{
+		Text:              text,
+		SwitchInlineQuery: &query,
+	}
+}
+
+func NewLoginURLButton(text string, loginURL LoginURL) InlineKeyboardButton {
+	return InlineKeyboardButton{
+		Text:     text,
+		LoginURL: &loginURL,
+	}
+}
+
 func NewInlineKeyboard(markup InlineKeyboardButtonsMarkup, buttons ...InlineKeyboardButton) *InlineKeyboard {
-	var rows [][]tg.InlineKeyboardButton
+	layout := NewInlineKeyboardLayout()
 
 	switch markup {
 
 	case InRowButtonsMarkup:
-		row := make([]tg.InlineKeyboardButton, 0, len(buttons))
-		for _, button := range buttons {
-			row = append(row, tg.NewInlineKeyboardButtonData(button.Text, button.Command))
-		}
-		rows = append(rows, row)
+		layout.Row(buttons...)
 
 	case InColButtonsMarkup:
-		for _, button := range buttons {
-			rows = append(rows, []tg.InlineKeyboardButton{
-				tg.NewInlineKeyboardButtonData(button.Text, button.Command),
-			})
+		layout.Grid(1, buttons...)
+	}
+
+	return layout.Keyboard()
+}
+
+// InlineKeyboardLayout builds inline keyboard from explicit rows and grids.
+type InlineKeyboardLayout struct {
+	rows [][]tg.InlineKeyboardButton
+}
+
+func NewInlineKeyboardLayout() *InlineKeyboardLayout {
+	return &InlineKeyboardLayout{}
+}
+
+// Row appends a single row with all buttons. Empty rows are skipped.
+func (l *InlineKeyboardLayout) Row(buttons ...InlineKeyboardButton) *InlineKeyboardLayout {
+	if len(buttons) == 0 {
+		return l
+	}
+	row := make([]tg.InlineKeyboardButton, 0, len(buttons))
+
+	for _, button := range buttons {
+		row = append(row, button.apiButton())
+	}
+	l.rows = append(l.rows, row)
+
+	return l
+}
+
+// Grid appends rows with perRow buttons in each. The last row may be shorter.
+func (l *InlineKeyboardLayout) Grid(perRow int, buttons ...InlineKeyboardButton) *InlineKeyboardLayout {
+	if perRow <= 0 {
+		perRow = 1
+	}
+	for start := 0; start < len(buttons); start += perRow {
+		end := start + perRow
+
+		if end > len(buttons) {
+			end = len(buttons)
 		}
+		l.Row(buttons[start:end]...)
 	}
+	return l
+}
 
+func (l *InlineKeyboardLayout) Keyboard() *InlineKeyboard {
 	return &InlineKeyboard{
-		markup: tg.NewInlineKeyboardMarkup(rows...),
+		markup: tg.NewInlineKeyboardMarkup(l.rows...),
+	}
+}
+
+func (b InlineKeyboardButton) apiButton() tg.InlineKeyboardButton {
+	switch {
+	case b.LoginURL != nil:
+		return tg.InlineKeyboardButton{
+			Text: b.Text,
+			LoginURL: &tg.LoginURL{
+				URL:                b.LoginURL.URL,
+				ForwardText:        b.LoginURL.ForwardText,
+				BotUsername:        b.LoginURL.BotUsername,
+				RequestWriteAccess: b.LoginURL.RequestWriteAccess,
+			},
+		}
+	case b.URL != "":
+		return tg.NewInlineKeyboardButtonURL(b.Text, b.URL)
+	case b.SwitchInlineQuery != nil:
+		return tg.NewInlineKeyboardButtonSwitch(b.Text, *b.SwitchInlineQuery)
+	case b.SwitchInlineQueryCurrentChat != nil:
+		query := *b.SwitchInlineQueryCurrentChat
+
+		return tg.InlineKeyboardButton{
+			Text:                         b.Text,
+			SwitchInlineQueryCurrentChat: &query,
+		}
+	default:
+		return tg.NewInlineKeyboardButtonData(b.Text, b.Command)
 	}
 }
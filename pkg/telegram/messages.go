@@ -25,7 +25,9 @@ type Message struct {
 	Text         string
 	Command      string
 	Date         int64
+	CallbackID   string
 	fromCallback bool
+	callbackErr  error
 }
 
 type SendMessage struct {
@@ -53,14 +55,22 @@ func (m *Message) FromCallback() bool {
 	return m.fromCallback
 }
 
+// CallbackError returns error if callback data cannot be decoded, e.g. it is expired.
+func (m *Message) CallbackError() error {
+	return m.callbackErr
+}
+
 func apiCallbackToModel(cb *tg.CallbackQuery) *Message {
 	var (
-		chatID   int64
-		userID   int64
-		userName string
-		date     int64
+		messageID int64
+		chatID    int64
+		userID    int64
+		userName  string
+		date      int64
 	)
 	if m := cb.Message; m != nil {
+		messageID = int64(m.MessageID)
+
 		if chat := m.Chat; chat != nil {
 			chatID = chat.ID
 		}
@@ -73,12 +83,14 @@ func apiCallbackToModel(cb *tg.CallbackQuery) *Message {
 	data := strings.TrimSpace(cb.Data)
 
 	return &Message{
+		MessageID:    messageID,
 		ChatID:       chatID,
 		UserID:       userID,
 		UserName:     userName,
 		Text:         data,
 		Command:      strings.TrimPrefix(data, "/"),
 		Date:         date,
+		CallbackID:   cb.ID,
 		fromCallback: true,
 	}
 }
@@ -107,18 +119,19 @@ func apiMessageToModel(msg *tg.Message) *Message {
 	}
 }
 
-func (m *SendMessage) apiInlineKeyboard() any {
+func (m *SendMessage) apiInlineKeyboard(codec CallbackCodec) (any, error) {
 	if m.Keyboard == nil {
-		return nil
+		return nil, nil
+	}
+	markup, err := encodeKeyboard(codec, m.ChatID, m.Keyboard)
+	if err != nil {
+		return nil, err
 	}
-	return m.Keyboard.markup
+	return *markup, nil
 }
 
-func (m *EditMessage) apiInlineKeyboard() *tg.InlineKeyboardMarkup {
-	if m.Keyboard == nil {
-		return nil
-	}
-	return &m.Keyboard.markup
+func (m *EditMessage) apiInlineKeyboard(codec CallbackCodec) (*tg.InlineKeyboardMarkup, error) {
+	return encodeKeyboard(codec, m.ChatID, m.Keyboard)
 }
 
 func (m *SendMessage) ToEditMessage(messageID int64) *EditMessage {
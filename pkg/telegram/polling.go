@@ -0,0 +1,175 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	pollTimeout   = 30
+	pollBuffer    = 100
+	pollRetryWait = 3 * time.Second
+	// dispatched updates are confirmed before polling stops
+	pollConfirmTimeout = 5 * time.Second
+)
+
+// poller receives updates with getUpdates only while Poll is running,
+// so only one of bot instances, e.g. elected leader, receives updates.
+type poller struct {
+	mtx     sync.Mutex
+	wg      sync.WaitGroup
+	updates chan tg.Update
+	done    chan struct{}
+	running chan struct{}
+	closed  bool
+	offset  int
+}
+
+func (b *bot) startPolling() error {
+	// updates cannot be received with getUpdates while webhook is set
+	if _, err := b.api.Request(tg.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("cannot delete telegram webhook: %v", err)
+	}
+	b.poller = &poller{
+		updates: make(chan tg.Update, pollBuffer),
+		done:    make(chan struct{}),
+		running: make(chan struct{}, 1),
+	}
+	b.updates = b.poller.updates
+
+	return nil
+}
+
+// Poll receives updates until context is cancelled or bot is shutdown.
+// Updates are received by webhook server in webhook mode, so poll returns immediately.
+func (b *bot) Poll(ctx context.Context) {
+	p := b.poller
+
+	if p == nil {
+		return
+	}
+	p.mtx.Lock()
+
+	if p.closed {
+		p.mtx.Unlock()
+		return
+	}
+	p.wg.Add(1)
+	p.mtx.Unlock()
+
+	defer p.wg.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-p.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	// concurrent getUpdates requests terminate each other
+	select {
+	case p.running <- struct{}{}:
+		defer func() { <-p.running }()
+	case <-ctx.Done():
+		return
+	}
+	log.Infof("telegram updates polling started")
+
+	// other instance polls from offset confirmed by telegram, so dispatched updates are not received again
+	defer b.confirmUpdates(p)
+
+	for {
+		updates, err := b.getUpdates(ctx, p.offset, pollTimeout)
+		if ctx.Err() != nil {
+			// updates received after cancel are not confirmed and received again by next poll
+			log.Infof("telegram updates polling stopped")
+			return
+		}
+		if err != nil {
+			log.Warnf("cannot get telegram updates: %v", err)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollRetryWait):
+			}
+			continue
+		}
+		for _, update := range updates {
+			if update.UpdateID < p.offset {
+				continue
+			}
+			select {
+			case p.updates <- update:
+			case <-ctx.Done():
+				// update is not dispatched, so it is received again by next poll
+				return
+			}
+			p.offset = update.UpdateID + 1
+		}
+	}
+}
+
+// confirmUpdates confirms dispatched updates with short getUpdates request from next offset.
+func (b *bot) confirmUpdates(p *poller) {
+	if p.offset == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pollConfirmTimeout)
+	defer cancel()
+
+	if _, err := b.getUpdates(ctx, p.offset, 0); err != nil {
+		log.Warnf("cannot confirm telegram updates: %v", err)
+	}
+}
+
+// getUpdates does long polling request which is cancelled with context,
+// so stopped poller does not conflict with getUpdates of other instance.
+func (b *bot) getUpdates(ctx context.Context, offset, timeout int) ([]tg.Update, error) {
+	api := *b.api
+	api.Client = &contextClient{ctx: ctx, client: b.api.Client}
+
+	return api.GetUpdates(tg.UpdateConfig{
+		Offset:  offset,
+		Timeout: timeout,
+	})
+}
+
+// contextClient binds api requests to context, since api does not accept context.
+type contextClient struct {
+	ctx    context.Context
+	client tg.HTTPClient
+}
+
+func (c *contextClient) Do(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req.WithContext(c.ctx))
+}
+
+func (b *bot) shutdownPolling() {
+	p := b.poller
+
+	if p == nil {
+		return
+	}
+	p.mtx.Lock()
+
+	if p.closed {
+		p.mtx.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.mtx.Unlock()
+
+	// no pollers left to write updates
+	p.wg.Wait()
+	close(p.updates)
+}
@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestGetUpdatesCancelsRequest(t *testing.T) {
+	cancelled := make(chan struct{})
+
+	// server holds long polling request until client cancels it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// disconnect is detected after request body is read
+		_, _ = io.Copy(io.Discard, r.Body)
+
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	api := &tg.BotAPI{Token: "token", Client: server.Client()}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+
+	b := &bot{api: api}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if _, err := b.getUpdates(ctx, 0, pollTimeout); err == nil || time.Since(start) > time.Second {
+		t.Fatalf("getUpdates returned %v after %s, want cancel error on context timeout", err, time.Since(start))
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("getUpdates request is not cancelled on server")
+	}
+}
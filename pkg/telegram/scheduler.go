@@ -0,0 +1,167 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultGlobalRate    = 30
+	defaultChatInterval  = time.Second
+	defaultGroupInterval = 3 * time.Second
+
+	// sweep chats slots when map exceeds this size, then when map doubles since last sweep
+	chatsSlotsSweepSize = 10000
+)
+
+// Scheduler paces outbound telegram requests with global, per chat and group chat limits.
+type Scheduler interface {
+	Wait(ctx context.Context, chatID int64) error
+	Pause(d time.Duration)
+}
+
+type SchedulerConfig struct {
+	GlobalRate    int           `yaml:"global_rate"`
+	ChatInterval  time.Duration `yaml:"chat_interval"`
+	GroupInterval time.Duration `yaml:"group_interval"`
+}
+
+type scheduler struct {
+	mtx            sync.Mutex
+	globalInterval time.Duration
+	chatInterval   time.Duration
+	groupInterval  time.Duration
+	globalNext     time.Time
+	pausedUntil    time.Time
+	chatsNext      map[int64]time.Time
+	// chatsSweepSize grows with chats left after sweep, so sweeps of mostly future slots are amortized
+	chatsSweepSize int
+}
+
+func NewScheduler(config *SchedulerConfig) Scheduler {
+	s := &scheduler{
+		globalInterval: time.Second / defaultGlobalRate,
+		chatInterval:   defaultChatInterval,
+		groupInterval:  defaultGroupInterval,
+		chatsNext:      map[int64]time.Time{},
+		chatsSweepSize: chatsSlotsSweepSize,
+	}
+	if config == nil {
+		return s
+	}
+	if config.GlobalRate > 0 {
+		s.globalInterval = time.Second / time.Duration(config.GlobalRate)
+	}
+	if config.ChatInterval > 0 {
+		s.chatInterval = config.ChatInterval
+	}
+	if config.GroupInterval > 0 {
+		s.groupInterval = config.GroupInterval
+	}
+	return s
+}
+
+// Wait blocks until request to chat is allowed by every limit or context is done.
+// Slots are reserved in call order, so waiting requests are served first in first out.
+func (s *scheduler) Wait(ctx context.Context, chatID int64) error {
+	for {
+		slot := s.reserve(chatID)
+
+		if wait := time.Until(slot); wait > 0 {
+			t := time.NewTimer(wait)
+
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+		// queue may be paused while waiting for reserved slot
+		if !s.isPaused() {
+			return nil
+		}
+	}
+}
+
+// Pause stops all outbound requests for duration, e.g. after 429 response.
+func (s *scheduler) Pause(d time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if until := time.Now().Add(d); until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+func (s *scheduler) reserve(chatID int64) time.Time {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	slot := latest(now, s.pausedUntil, s.globalNext, s.chatsNext[chatID])
+
+	interval := s.chatInterval
+
+	// group and channel chats have negative ids
+	if chatID < 0 {
+		interval = s.groupInterval
+	}
+	s.globalNext = slot.Add(s.globalInterval)
+	s.chatsNext[chatID] = slot.Add(interval)
+
+	if len(s.chatsNext) > s.chatsSweepSize {
+		s.sweepChats(now)
+	}
+	return slot
+}
+
+// sweepChats removes passed chats slots. Must be called with mutex held.
+func (s *scheduler) sweepChats(now time.Time) {
+	for id, next := range s.chatsNext {
+		if next.Before(now) {
+			delete(s.chatsNext, id)
+		}
+	}
+	s.chatsSweepSize = chatsSlotsSweepSize
+
+	if size := 2 * len(s.chatsNext); size > s.chatsSweepSize {
+		s.chatsSweepSize = size
+	}
+}
+
+func (s *scheduler) isPaused() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return time.Now().Before(s.pausedUntil)
+}
+
+func latest(t time.Time, ts ...time.Time) time.Time {
+	for _, other := range ts {
+		if other.After(t) {
+			t = other
+		}
+	}
+	return t
+}
+
+// pauseOnFlood pauses scheduler if telegram responded with retry_after and reports whether it did.
+func pauseOnFlood(s Scheduler, err error) bool {
+	var apiErr *tg.Error
+
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+		return false
+	}
+	d := time.Duration(apiErr.RetryAfter) * time.Second
+
+	log.Warnf("telegram flood limit exceeded. outbound requests paused for %s", d)
+	s.Pause(d)
+
+	return true
+}
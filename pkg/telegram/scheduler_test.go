@@ -0,0 +1,54 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerPacesChat(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{
+		GlobalRate:    1000,
+		ChatInterval:  50 * time.Millisecond,
+		GroupInterval: 100 * time.Millisecond,
+	})
+	ctx := context.Background()
+	start := time.Now()
+
+	for _, chatID := range []int64{1, 2, 1} {
+		if err := s.Wait(ctx, chatID); err != nil {
+			t.Fatalf("Wait error = %v", err)
+		}
+	}
+	// other chat is not paced by first chat interval
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed >= 100*time.Millisecond {
+		t.Fatalf("three requests to two chats took %s, want single chat interval", elapsed)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if err := s.Wait(ctx, 1); err == nil {
+		t.Fatalf("Wait of paced chat with cancelled context returned no error")
+	}
+}
+
+func TestSchedulerSweepsChatsAmortized(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{ChatInterval: time.Hour}).(*scheduler)
+
+	// slots of all chats are in future, so sweep removes nothing
+	for chatID := int64(1); chatID <= chatsSlotsSweepSize+1; chatID++ {
+		s.reserve(chatID)
+	}
+	if want := 2 * (chatsSlotsSweepSize + 1); s.chatsSweepSize != want {
+		t.Fatalf("sweep size after sweep of future slots = %d, want %d", s.chatsSweepSize, want)
+	}
+	// passed slots are removed and sweep size returns to default
+	for id := range s.chatsNext {
+		s.chatsNext[id] = time.Now().Add(-time.Second)
+	}
+	s.sweepChats(time.Now())
+
+	if len(s.chatsNext) != 0 || s.chatsSweepSize != chatsSlotsSweepSize {
+		t.Fatalf("chats = %d, sweep size = %d after sweep of passed slots", len(s.chatsNext), s.chatsSweepSize)
+	}
+}
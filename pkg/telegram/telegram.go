@@ -2,8 +2,13 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"main/pkg/cache"
 	"main/pkg/retries"
+	"net/http"
+	"runtime/debug"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -18,81 +23,101 @@ const (
 
 type Bot interface {
 	Start() error
-	StartWithWebhook(link string) error
 	SendMessage(m *SendMessage, options ...MessageOption) (int64, error)
 	EditMessage(m *EditMessage, options ...MessageOption) (int64, error)
+	EditKeyboard(chatID int64, messageID int64, keyboard *InlineKeyboard) error
 	DeleteMessage(chatID int64, messageID int64) error
+	AnswerCallback(callbackID string, options ...CallbackOption) error
 	HandleMessages(handler func(m *Message) error)
+	Poll(ctx context.Context)
 	Shutdown()
 }
 
 type bot struct {
-	ctx     context.Context
-	token   string
-	api     *tg.BotAPI
-	updates tg.UpdatesChannel
-	started chan struct{}
+	ctx          context.Context
+	config       *Config
+	api          *tg.BotAPI
+	updates      tg.UpdatesChannel
+	closeUpdates func()
+	poller       *poller
+	server       *http.Server
+	scheduler    Scheduler
+	codec        CallbackCodec
+	callbacks    *callbacks
 }
 
-func NewBot(ctx context.Context, token string) (Bot, error) {
-	api, err := tg.NewBotAPI(token)
+func NewBot(ctx context.Context, config *Config, caches *cache.Backend) (Bot, error) {
+	api, err := tg.NewBotAPI(config.Token)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create new bot api: %v", err)
 	}
+	codec, err := NewCallbackCodec(config.Callback, caches)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create callback codec: %v", err)
+	}
 	return &bot{
-		ctx: ctx,
-		api: api,
+		ctx:       ctx,
+		config:    config,
+		api:       api,
+		scheduler: NewScheduler(config.Scheduler),
+		codec:     codec,
+		callbacks: newCallbacks(),
 	}, nil
 }
 
 func (b *bot) Start() error {
-	const timeout = 60
-
-	b.updates = b.api.GetUpdatesChan(tg.UpdateConfig{
-		Offset:  0,
-		Limit:   0,
-		Timeout: timeout,
-	})
-	return nil
-}
-
-func (b *bot) StartWithWebhook(link string) error {
-	wh, err := tg.NewWebhook(link)
-	if err != nil {
-		return fmt.Errorf("cannot create new telegram webhook: %v", err)
+	switch mode := b.config.Mode; mode {
+	case "", PollingMode:
+		return b.startPolling()
+	case WebhookMode:
+		return b.startWebhook(b.config.Webhook)
+	default:
+		return fmt.Errorf("unsupported telegram bot mode: %s", mode)
 	}
-	if _, err := b.api.Request(wh); err != nil {
-		return fmt.Errorf("cannot do request to webhook: %v", err)
-	}
-	info, err := b.api.GetWebhookInfo()
-	if err != nil {
-		return fmt.Errorf("cannot get webhook info: %v", err)
-	}
-	if info.LastErrorDate != 0 {
-		return fmt.Errorf("telegram callback failed: %s", info.LastErrorMessage)
-	}
-	b.updates = b.api.ListenForWebhook("/" + b.token)
-
-	return nil
 }
 
+// HandleMessages dispatches updates to workers sharded by chat id.
+// Updates of one chat are handled in order, updates of different chats are handled in parallel.
 func (b *bot) HandleMessages(handler func(m *Message) error) {
-	var (
-		m   *Message
-		err error
-	)
+	w := newWorkers(b.config.Workers, func(m *Message) {
+		b.handleMessage(handler, m)
+	})
+	defer w.stop()
+
 	for update := range b.updates {
+		var m *Message
+
 		if msg := update.Message; msg != nil {
 			m = apiMessageToModel(msg)
 		}
 		if cb := update.CallbackQuery; cb != nil {
 			m = apiCallbackToModel(cb)
+
+			// callback data is bound to chat where button was sent
+			data, err := b.codec.Decode(m.ChatID, cb.Data)
+			if err != nil {
+				data = ""
+			}
+			m.Text = data
+			m.Command = strings.TrimPrefix(data, "/")
+			m.callbackErr = err
 		}
 		if m != nil {
-			if err = handler(m); err != nil {
-				log.Errorf("cannot handle telegram message: %v", err)
-			}
+			w.dispatch(m)
+		}
+	}
+}
+
+func (b *bot) handleMessage(handler func(m *Message) error, m *Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("telegram message handler panic for chat with id %d: %v\n%s", m.ChatID, r, debug.Stack())
 		}
+		// stop telegram client spinner for pressed button
+		b.ensureCallbackAnswered(m)
+	}()
+	if err := handler(m); err != nil {
+		log.Errorf("cannot handle telegram message: %v", err)
 	}
 }
 
@@ -131,16 +156,23 @@ func (b *bot) SendMessage(m *SendMessage, options ...MessageOption) (int64, erro
 	)
 	mo := callMessageOptions(options...)
 
+	keyboard, err := m.apiInlineKeyboard(b.codec)
+	if err != nil {
+		return 0, fmt.Errorf("cannot build telegram message keyboard: %v", err)
+	}
 	err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err = b.scheduler.Wait(b.ctx, m.ChatID); err != nil {
+			return fmt.Errorf("cannot wait telegram outbound scheduler: %v", err)
+		}
 		if msg, err = b.api.Send(tg.MessageConfig{
 			BaseChat: tg.BaseChat{
 				ChatID:      m.ChatID,
-				ReplyMarkup: m.apiInlineKeyboard(),
+				ReplyMarkup: keyboard,
 			},
 			Text:      m.Text,
 			ParseMode: mo.parseMode.String(),
 		}); err != nil {
-			return fmt.Errorf("%w: cannot send telegram message: %v", retries.ErrDoRetry, err)
+			return b.requestError(m.ChatID, "cannot send telegram message", err)
 		}
 		id = int64(msg.MessageID)
 		return nil
@@ -156,17 +188,28 @@ func (b *bot) EditMessage(m *EditMessage, options ...MessageOption) (int64, erro
 	)
 	mo := callMessageOptions(options...)
 
+	keyboard, err := m.apiInlineKeyboard(b.codec)
+	if err != nil {
+		return 0, fmt.Errorf("cannot build telegram message keyboard: %v", err)
+	}
 	err = retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err = b.scheduler.Wait(b.ctx, m.ChatID); err != nil {
+			return fmt.Errorf("cannot wait telegram outbound scheduler: %v", err)
+		}
 		if msg, err = b.api.Send(tg.EditMessageTextConfig{
 			BaseEdit: tg.BaseEdit{
 				ChatID:      m.ChatID,
 				MessageID:   int(m.MessageID),
-				ReplyMarkup: m.apiInlineKeyboard(),
+				ReplyMarkup: keyboard,
 			},
 			Text:      m.Text,
 			ParseMode: mo.parseMode.String(),
 		}); err != nil {
-			return fmt.Errorf("%w: cannot edit telegram message: %v", retries.ErrDoRetry, err)
+			if err = b.requestError(m.ChatID, "cannot edit telegram message", err); errors.Is(err, ErrMessageNotModified) {
+				id = m.MessageID
+				return nil
+			}
+			return err
 		}
 		id = int64(msg.MessageID)
 		return nil
@@ -174,18 +217,60 @@ func (b *bot) EditMessage(m *EditMessage, options ...MessageOption) (int64, erro
 	return id, err
 }
 
+func (b *bot) EditKeyboard(chatID int64, messageID int64, keyboard *InlineKeyboard) error {
+	markup, err := encodeKeyboard(b.codec, chatID, keyboard)
+	if err != nil {
+		return fmt.Errorf("cannot build telegram message keyboard: %v", err)
+	}
+	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err := b.scheduler.Wait(b.ctx, chatID); err != nil {
+			return fmt.Errorf("cannot wait telegram outbound scheduler: %v", err)
+		}
+		if _, err := b.api.Request(tg.EditMessageReplyMarkupConfig{
+			BaseEdit: tg.BaseEdit{
+				ChatID:      chatID,
+				MessageID:   int(messageID),
+				ReplyMarkup: markup,
+			},
+		}); err != nil {
+			if err = b.requestError(chatID, "cannot edit telegram message keyboard", err); errors.Is(err, ErrMessageNotModified) {
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+}
+
 func (b *bot) DeleteMessage(chatID int64, messageID int64) error {
 	return retries.DoWithRetries(retryCount, retryWait, func() error {
+		if err := b.scheduler.Wait(b.ctx, chatID); err != nil {
+			return fmt.Errorf("cannot wait telegram outbound scheduler: %v", err)
+		}
 		if _, err := b.api.Request(tg.DeleteMessageConfig{
 			ChatID:    chatID,
 			MessageID: int(messageID),
 		}); err != nil {
-			return fmt.Errorf("%w: cannot delete telegram message: %v", retries.ErrDoRetry, err)
+			return b.requestError(chatID, "cannot delete telegram message", err)
 		}
 		return nil
 	})
 }
 
+// requestError classifies failed request error. Permanent chat errors are not retried.
+func (b *bot) requestError(chatID int64, message string, err error) error {
+	if chatErr := classifyError(chatID, err); chatErr != nil {
+		return fmt.Errorf("%s: %w", message, chatErr)
+	}
+	pauseOnFlood(b.scheduler, err)
+
+	return fmt.Errorf("%w: %s: %v", retries.ErrDoRetry, message, err)
+}
+
 func (b *bot) Shutdown() {
-	b.api.StopReceivingUpdates()
+	if b.server != nil {
+		b.shutdownWebhook()
+		return
+	}
+	b.shutdownPolling()
 }
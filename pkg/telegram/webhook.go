@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"main/pkg/validation"
+	"net/http"
+	"net/url"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	webhookBuffer     = 100
+	shutdownTimeout   = 5 * time.Second
+)
+
+func (b *bot) startWebhook(config *WebhookConfig) error {
+	if config == nil {
+		return fmt.Errorf("webhook config not specified")
+	}
+	if err := validation.ValidateStructFields(config); err != nil {
+		return fmt.Errorf("cannot validate webhook config: %v", err)
+	}
+	link, err := url.Parse(config.URL)
+	if err != nil {
+		return fmt.Errorf("cannot parse webhook url: %v", err)
+	}
+	if err = b.setWebhook(config); err != nil {
+		return fmt.Errorf("cannot set telegram webhook: %v", err)
+	}
+	path := link.Path
+
+	if path == "" {
+		path = "/"
+	}
+	updates := make(chan tg.Update, webhookBuffer)
+
+	mux := http.NewServeMux()
+	mux.Handle(path, b.webhookHandler(config.Secret, updates))
+
+	b.server = &http.Server{
+		Addr:    config.Listen,
+		Handler: mux,
+	}
+	b.updates = updates
+	b.closeUpdates = func() { close(updates) }
+
+	go func() {
+		var err error
+
+		if config.CertFile != "" && config.KeyFile != "" {
+			err = b.server.ListenAndServeTLS(config.CertFile, config.KeyFile)
+		} else {
+			err = b.server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("telegram webhook server stopped: %v", err)
+		}
+	}()
+	log.Infof("telegram webhook server listen on %s", config.Listen)
+
+	return nil
+}
+
+func (b *bot) setWebhook(config *WebhookConfig) error {
+	const endpoint = "setWebhook"
+
+	params := tg.Params{}
+
+	params.AddNonEmpty("url", config.URL)
+	params.AddNonEmpty("secret_token", config.Secret)
+
+	var err error
+
+	// self-signed certificate must be uploaded to telegram
+	if config.CertFile != "" {
+		_, err = b.api.UploadFiles(endpoint, params, []tg.RequestFile{
+			{
+				Name: "certificate",
+				Data: tg.FilePath(config.CertFile),
+			},
+		})
+	} else {
+		_, err = b.api.MakeRequest(endpoint, params)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot do %s request: %v", endpoint, err)
+	}
+	info, err := b.api.GetWebhookInfo()
+	if err != nil {
+		return fmt.Errorf("cannot get webhook info: %v", err)
+	}
+	if info.LastErrorDate != 0 {
+		log.Warnf("telegram webhook last error: %s", info.LastErrorMessage)
+	}
+	return nil
+}
+
+func (b *bot) webhookHandler(secret string, updates chan<- tg.Update) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if secret != "" {
+			token := r.Header.Get(secretTokenHeader)
+
+			if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		update, err := b.api.HandleUpdate(r)
+		if err != nil {
+			log.Warnf("cannot handle telegram webhook update: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		select {
+		case updates <- *update:
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+}
+
+func (b *bot) shutdownWebhook() {
+	if b.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := b.server.Shutdown(ctx); err != nil {
+		log.Errorf("cannot shutdown telegram webhook server: %v", err)
+		return
+	}
+	// no handlers left to write updates
+	b.closeUpdates()
+}
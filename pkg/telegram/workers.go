@@ -0,0 +1,68 @@
+package telegram
+
+import "sync"
+
+const (
+	defaultWorkersCount = 16
+	defaultWorkersQueue = 100
+)
+
+type WorkersConfig struct {
+	Count int `yaml:"count"`
+	Queue int `yaml:"queue"`
+}
+
+// workers handle messages in shards by chat id, so messages of one chat keep order.
+type workers struct {
+	wg     sync.WaitGroup
+	shards []chan *Message
+}
+
+func newWorkers(config *WorkersConfig, handle func(m *Message)) *workers {
+	count, queue := defaultWorkersCount, defaultWorkersQueue
+
+	if config != nil {
+		if config.Count > 0 {
+			count = config.Count
+		}
+		if config.Queue > 0 {
+			queue = config.Queue
+		}
+	}
+	w := &workers{
+		shards: make([]chan *Message, count),
+	}
+	for i := range w.shards {
+		shard := make(chan *Message, queue)
+		w.shards[i] = shard
+
+		w.wg.Add(1)
+
+		go func() {
+			defer w.wg.Done()
+
+			for m := range shard {
+				handle(m)
+			}
+		}()
+	}
+	return w
+}
+
+// dispatch blocks if shard queue is full, so slow chat applies backpressure to updates receiving.
+func (w *workers) dispatch(m *Message) {
+	w.shards[w.shard(m.ChatID)] <- m
+}
+
+func (w *workers) shard(chatID int64) int {
+	// group chats have negative ids
+	return int(uint64(chatID) % uint64(len(w.shards)))
+}
+
+// stop waits until queued messages are handled.
+func (w *workers) stop() {
+	for _, shard := range w.shards {
+		close(shard)
+	}
+	w.wg.Wait()
+}
@@ -28,7 +28,9 @@ func ValidateStructFields(structPtr any) error {
 					refType.Field(fieldIdx).Name, tagKey, tagValue)
 			}
 			if fieldIface := fieldVal.Interface(); validateStructPtr(fieldIface) == nil {
-				return ValidateStructFields(fieldIface)
+				if err := ValidateStructFields(fieldIface); err != nil {
+					return err
+				}
 			}
 		}
 	}